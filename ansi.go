@@ -0,0 +1,164 @@
+package radiko
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	ansiBold  = "\x1b[1m"
+	ansiReset = "\x1b[0m"
+)
+
+// DescANSI returns the program's description and info for terminals.
+// The HTML tags are stripped, and the text is wrapped to the given width
+// counting wide characters as two columns.
+// The title is bolded on the first line if it is not empty.
+// If width is not positive, the text is not wrapped.
+func (p Prog) DescANSI(width int) string {
+	var lines []string
+	if p.Title != "" {
+		lines = append(lines, ansiBold+p.Title+ansiReset)
+	}
+	for _, s := range []string{p.Desc, p.Info} {
+		text := stripHTML(s)
+		if text == "" {
+			continue
+		}
+		for _, paragraph := range strings.Split(text, "\n") {
+			lines = append(lines, wrapText(paragraph, width)...)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stripHTML returns the text content of s.
+// The line break and block elements are converted to newlines.
+func stripHTML(s string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(collapseNewlines(b.String()))
+		case html.TextToken:
+			b.Write(z.Text())
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "br", "p", "div", "li":
+				b.WriteByte('\n')
+			}
+		}
+	}
+}
+
+func collapseNewlines(s string) string {
+	lines := strings.Split(s, "\n")
+	result := lines[:0]
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if l != "" {
+			result = append(result, l)
+		}
+	}
+	return strings.Join(result, "\n")
+}
+
+// wrapText wraps s to the width.
+// The words are not broken unless they are longer than the width,
+// and the wide characters can be broken anywhere.
+func wrapText(s string, width int) []string {
+	if width <= 0 {
+		return []string{s}
+	}
+
+	var (
+		lines []string
+		line  []rune
+		lineW int
+	)
+	flush := func() {
+		lines = append(lines, strings.TrimRightFunc(string(line), unicode.IsSpace))
+		line, lineW = line[:0], 0
+	}
+	add := func(seg []rune) {
+		w := stringWidth(seg)
+		if lineW+w > width && lineW > 0 {
+			flush()
+			if len(seg) == 1 && unicode.IsSpace(seg[0]) {
+				return
+			}
+		}
+		for w > width {
+			// hard-split a segment longer than the width
+			var n, i int
+			for i = 0; i < len(seg) && n+runeWidth(seg[i]) <= width; i++ {
+				n += runeWidth(seg[i])
+			}
+			line = append(line, seg[:i]...)
+			flush()
+			seg = seg[i:]
+			w -= n
+		}
+		line = append(line, seg...)
+		lineW += w
+	}
+
+	var word []rune
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			if len(word) > 0 {
+				add(word)
+				word = nil
+			}
+			add([]rune{' '})
+		case runeWidth(r) == 2:
+			if len(word) > 0 {
+				add(word)
+				word = nil
+			}
+			add([]rune{r})
+		default:
+			word = append(word, r)
+		}
+	}
+	if len(word) > 0 {
+		add(word)
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		flush()
+	}
+	return lines
+}
+
+func stringWidth(rs []rune) int {
+	var w int
+	for _, r := range rs {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// runeWidth returns the number of columns r occupies in terminals.
+func runeWidth(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0x303E, // CJK Radicals, Symbols and Punctuation
+		r >= 0x3041 && r <= 0x33FF, // Hiragana, Katakana, CJK Compatibility
+		r >= 0x3400 && r <= 0x4DBF, // CJK Unified Ideographs Extension A
+		r >= 0x4E00 && r <= 0x9FFF, // CJK Unified Ideographs
+		r >= 0xA960 && r <= 0xA97F, // Hangul Jamo Extended-A
+		r >= 0xAC00 && r <= 0xD7A3, // Hangul Syllables
+		r >= 0xF900 && r <= 0xFAFF, // CJK Compatibility Ideographs
+		r >= 0xFE30 && r <= 0xFE4F, // CJK Compatibility Forms
+		r >= 0xFF00 && r <= 0xFF60, // Fullwidth Forms
+		r >= 0xFFE0 && r <= 0xFFE6, // Fullwidth Signs
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,37 @@
+package radiko
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProg_DescANSI(t *testing.T) {
+	prog := Prog{
+		Title: "ウィークエンド・シャッフル",
+		Desc:  "Weekend night talk show with 宇多丸 and guests.",
+		Info:  "<p>10時20分頃からは、「週刊映画時評ムービーウォッチメン」。</p><br/>Mail: <a href=\"mailto:utamaru@tbs.co.jp\">utamaru@tbs.co.jp</a>",
+	}
+
+	expected, err := ioutil.ReadFile(filepath.Join(testdataDir, "desc_ansi.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := prog.DescANSI(20); string(expected) != actual {
+		t.Errorf("expected\n%s\nbut\n%s", expected, actual)
+	}
+
+	for _, line := range strings.Split(prog.DescANSI(20), "\n")[1:] {
+		if w := stringWidth([]rune(line)); w > 20 {
+			t.Errorf("line is wider than 20: %d %q", w, line)
+		}
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	const expected = "a & b\nc"
+	if actual := stripHTML("<b>a &amp; b</b><br />c"); expected != actual {
+		t.Errorf("expected %q, but %q", expected, actual)
+	}
+}
@@ -1,7 +1,14 @@
 package radiko
 
 import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"path"
 
 	"golang.org/x/net/html"
 )
@@ -10,6 +17,134 @@ const (
 	areaURL = "http://radiko.jp/area"
 )
 
+// Area represents a radiko area.
+type Area struct {
+	ID         string
+	Name       string
+	RegionName string
+}
+
+// areaNames maps the areaID to the name radiko uses.
+var areaNames = map[string]string{
+	"JP1": "HOKKAIDO JAPAN", "JP2": "AOMORI JAPAN", "JP3": "IWATE JAPAN",
+	"JP4": "MIYAGI JAPAN", "JP5": "AKITA JAPAN", "JP6": "YAMAGATA JAPAN",
+	"JP7": "FUKUSHIMA JAPAN", "JP8": "IBARAKI JAPAN", "JP9": "TOCHIGI JAPAN",
+	"JP10": "GUNMA JAPAN", "JP11": "SAITAMA JAPAN", "JP12": "CHIBA JAPAN",
+	"JP13": "TOKYO JAPAN", "JP14": "KANAGAWA JAPAN", "JP15": "NIIGATA JAPAN",
+	"JP16": "TOYAMA JAPAN", "JP17": "ISHIKAWA JAPAN", "JP18": "FUKUI JAPAN",
+	"JP19": "YAMANASHI JAPAN", "JP20": "NAGANO JAPAN", "JP21": "GIFU JAPAN",
+	"JP22": "SHIZUOKA JAPAN", "JP23": "AICHI JAPAN", "JP24": "MIE JAPAN",
+	"JP25": "SHIGA JAPAN", "JP26": "KYOTO JAPAN", "JP27": "OSAKA JAPAN",
+	"JP28": "HYOGO JAPAN", "JP29": "NARA JAPAN", "JP30": "WAKAYAMA JAPAN",
+	"JP31": "TOTTORI JAPAN", "JP32": "SHIMANE JAPAN", "JP33": "OKAYAMA JAPAN",
+	"JP34": "HIROSHIMA JAPAN", "JP35": "YAMAGUCHI JAPAN", "JP36": "TOKUSHIMA JAPAN",
+	"JP37": "KAGAWA JAPAN", "JP38": "EHIME JAPAN", "JP39": "KOUCHI JAPAN",
+	"JP40": "FUKUOKA JAPAN", "JP41": "SAGA JAPAN", "JP42": "NAGASAKI JAPAN",
+	"JP43": "KUMAMOTO JAPAN", "JP44": "OITA JAPAN", "JP45": "MIYAZAKI JAPAN",
+	"JP46": "KAGOSHIMA JAPAN", "JP47": "OKINAWA JAPAN",
+}
+
+// ValidateAreaID returns ErrInvalidAreaID
+// if the id is not one of the radiko areas, JP1 to JP47.
+func ValidateAreaID(id string) error {
+	if _, ok := areaNames[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidAreaID, id)
+	}
+	return nil
+}
+
+// GetAreas returns all radiko areas from JP1 to JP47.
+// The region of each area is resolved from the region list of the stations.
+// The result is cached in the Client until Close is called.
+func (c *Client) GetAreas(ctx context.Context) ([]Area, error) {
+	c.mu.Lock()
+	cached := c.areas
+	c.mu.Unlock()
+	if cached != nil {
+		return append([]Area(nil), cached...), nil
+	}
+
+	var d regionsData
+	err := c.getXML(ctx, regionsEndpoint, &Params{}, func(r io.Reader) error {
+		d = regionsData{}
+		return decodeRegionsData(r, &d)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	regionNames := make(map[string]string)
+	for _, region := range d.Regions {
+		for _, station := range region.Stations {
+			for _, areaID := range station.AreaIDs {
+				if _, ok := regionNames[areaID]; !ok {
+					regionNames[areaID] = region.Name
+				}
+			}
+		}
+	}
+
+	areaIDs := allAreaIDs()
+	areas := make([]Area, len(areaIDs))
+	for i, id := range areaIDs {
+		areas[i] = Area{
+			ID:         id,
+			Name:       areaNames[id],
+			RegionName: regionNames[id],
+		}
+	}
+
+	c.mu.Lock()
+	c.areas = areas
+	c.mu.Unlock()
+
+	return append([]Area(nil), areas...), nil
+}
+
+// Region is a group of the areas like 関東 or 近畿,
+// with the stations in the region.
+type Region struct {
+	ID        string        `xml:"region_id,attr" json:"id"`
+	Name      string        `xml:"region_name,attr" json:"name"`
+	ASCIIName string        `xml:"ascii_name,attr" json:"ascii_name"`
+	Stations  RadioStations `xml:"station" json:"stations"`
+}
+
+// GetStationsByRegion returns all the stations grouped by the region,
+// in the order radiko lists them.
+// The regions which have no station are included with no Stations.
+func (c *Client) GetStationsByRegion(ctx context.Context) ([]Region, error) {
+	var d regionsData
+	err := c.getXML(ctx, regionsEndpoint, &Params{}, func(r io.Reader) error {
+		d = regionsData{}
+		return decodeRegionsData(r, &d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.Regions, nil
+}
+
+// regionsEndpoint is the endpoint of the stations in all the regions.
+var regionsEndpoint = path.Join(apiV3, "station/region/full.xml")
+
+// regionsData includes a response struct for client's users.
+type regionsData struct {
+	XMLName xml.Name `xml:"region"`
+	Regions []Region `xml:"stations"`
+}
+
+func decodeRegionsData(input io.Reader, regions *regionsData) error {
+	b, err := ioutil.ReadAll(input)
+	if err != nil {
+		return err
+	}
+	if err = unmarshalXML(b, regions); err != nil {
+		return err
+	}
+	return nil
+}
+
 // AreaID returns areaID.
 func AreaID() (string, error) {
 	resp, err := http.Get(areaURL)
@@ -26,6 +161,47 @@ func AreaID() (string, error) {
 	return processSpanNode(doc), nil
 }
 
+// areaOutsideJP is the areaID radiko returns outside Japan.
+const areaOutsideJP = "OUT"
+
+// DetectArea returns the area of the current IP detected by radiko,
+// and sets it as the Client's area.
+// If the IP is outside Japan, it returns ErrAreaRestricted.
+func (c *Client) DetectArea(ctx context.Context) (string, error) {
+	req, err := c.newRequest(ctx, "GET", "area", &Params{})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", readAPIError(resp)
+	}
+
+	doc, err := html.Parse(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	areaID := processSpanNode(doc)
+	switch areaID {
+	case "":
+		return "", errors.New("area not found")
+	case areaOutsideJP:
+		return "", ErrAreaRestricted
+	}
+
+	if err = c.SetAreaID(areaID); err != nil {
+		return "", err
+	}
+	return areaID, nil
+}
+
 func processSpanNode(n *html.Node) string {
 	var areaID string
 
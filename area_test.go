@@ -1,7 +1,12 @@
 package radiko
 
 import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"golang.org/x/net/html"
@@ -32,3 +37,130 @@ func TestProcessSpanNode(t *testing.T) {
 			"Failed to process span node.\nAreaID: %s", areaID)
 	}
 }
+
+func TestClient_DetectArea(t *testing.T) {
+	area := "JP27"
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/area" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`document.write('<span class="` + area + `">OSAKA JAPAN</span>');`))
+	}))
+	defer closer()
+
+	areaID, err := c.DetectArea(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "JP27"; expected != areaID || expected != c.AreaID() {
+		t.Errorf("expected %s, but %s (Client: %s)", expected, areaID, c.AreaID())
+	}
+
+	area = "OUT"
+	if _, err := c.DetectArea(context.Background()); err != ErrAreaRestricted {
+		t.Errorf("expected %s, but %v", ErrAreaRestricted, err)
+	}
+	if expected := "JP27"; expected != c.AreaID() {
+		t.Errorf("expected %s, but %s", expected, c.AreaID())
+	}
+}
+
+func TestClient_GetAreas(t *testing.T) {
+	var requests int32
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.ServeFile(w, r, filepath.Join(testdataDir, "region_full.xml"))
+	}))
+	defer closer()
+
+	areas, err := c.GetAreas(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 47; len(areas) != expected {
+		t.Fatalf("expected %d areas, but %d", expected, len(areas))
+	}
+
+	cases := []Area{
+		{ID: "JP1", Name: "HOKKAIDO JAPAN", RegionName: "北海道・東北"},
+		{ID: "JP13", Name: "TOKYO JAPAN", RegionName: "関東"},
+		{ID: "JP47", Name: "OKINAWA JAPAN", RegionName: ""},
+	}
+	for _, expected := range cases {
+		var actual Area
+		for _, a := range areas {
+			if a.ID == expected.ID {
+				actual = a
+			}
+		}
+		if expected != actual {
+			t.Errorf("expected %+v, but %+v", expected, actual)
+		}
+	}
+
+	if _, err = c.GetAreas(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if expected := int32(1); requests != expected {
+		t.Errorf("expected %d requests, but %d", expected, requests)
+	}
+}
+
+func TestClient_GetStationsByRegion(t *testing.T) {
+	var requested string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		http.ServeFile(w, r, filepath.Join(testdataDir, "region_full.xml"))
+	}))
+	defer closer()
+
+	regions, err := c.GetStationsByRegion(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/v3/station/region/full.xml"; expected != requested {
+		t.Errorf("expected %s, but %s", expected, requested)
+	}
+
+	cases := []struct {
+		id       string
+		name     string
+		stations string
+	}{
+		{"hokkaido-tohoku", "北海道・東北", "HBC RAB"},
+		{"kanto", "関東", "TBS YFM"},
+		{"okinawa", "沖縄", ""},
+	}
+	if len(regions) != len(cases) {
+		t.Fatalf("expected %d regions, but %d", len(cases), len(regions))
+	}
+	for i, expected := range cases {
+		var ids []string
+		for _, s := range regions[i].Stations {
+			ids = append(ids, s.ID)
+		}
+		if regions[i].ID != expected.id || regions[i].Name != expected.name || strings.Join(ids, " ") != expected.stations {
+			t.Errorf("expected %s %s [%s], but %s %s %v", expected.id, expected.name, expected.stations, regions[i].ID, regions[i].Name, ids)
+		}
+	}
+}
+
+func TestDecodeRegionsData_Charset(t *testing.T) {
+	f, err := os.Open(filepath.Join(testdataDir, "region_full_sjis.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var d regionsData
+	if err := decodeRegionsData(f, &d); err != nil {
+		t.Fatal(err)
+	}
+	if expected := 3; len(d.Regions) != expected {
+		t.Fatalf("expected %d regions, but %d", expected, len(d.Regions))
+	}
+	if expected := "関東"; expected != d.Regions[1].Name {
+		t.Errorf("expected %s, but %s", expected, d.Regions[1].Name)
+	}
+}
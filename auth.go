@@ -4,47 +4,194 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
-	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
 
+// AuthInfo is the result of the auth flow.
+// It can be saved and restored by SetAuthInfo to skip the auth flow.
+type AuthInfo struct {
+	AuthToken string `json:"auth_token"`
+	// AreaID is the area which the auth_token is valid for.
+	AreaID string `json:"area_id"`
+}
+
+// loadAuthKey returns the key which the partial key is cut from.
+var loadAuthKey = downloadBinary
+
 // AuthorizeToken returns an enables auth_token and error,
 // and sets auth_token in Client.
 // Is is a alias function that wraps Auth1Fms and Auth2Fms.
 func (c *Client) AuthorizeToken(ctx context.Context) (string, error) {
-	bin, err := downloadBinary()
+	info, err := c.authorize(ctx)
 	if err != nil {
 		return "", err
 	}
 
+	c.setAuthTokenHeader(info.AuthToken)
+	return info.AuthToken, nil
+}
+
+// Authorize runs the auth flow, and returns the auth_token
+// and the area resolved by radiko.
+// Both of them are set in the Client.
+func (c *Client) Authorize(ctx context.Context) (AuthInfo, error) {
+	info, err := c.authorize(ctx)
+	if err != nil {
+		return AuthInfo{}, err
+	}
+
+	c.SetAuthInfo(info)
+	return info, nil
+}
+
+// SetAuthInfo sets the auth_token and the area saved from Authorize.
+// If AreaID is empty or invalid, the Client's area is not changed.
+func (c *Client) SetAuthInfo(info AuthInfo) {
+	c.setAuthTokenHeader(info.AuthToken)
+	if info.AreaID != "" {
+		c.SetAreaID(info.AreaID)
+	}
+}
+
+func (c *Client) authorize(ctx context.Context) (AuthInfo, error) {
+	bin, err := loadAuthKey()
+	if err != nil {
+		return AuthInfo{}, err
+	}
+
 	f := bytes.NewReader(bin)
 
 	authToken, length, offset, err := c.Auth1Fms(ctx)
 	if err != nil {
-		return "", err
+		return AuthInfo{}, err
 	}
 
 	b := make([]byte, length)
 	io.CopyN(ioutil.Discard, f, offset)
 	if _, err = f.Read(b); err != nil {
-		return "", err
+		return AuthInfo{}, err
 	}
 	partialKey := base64.StdEncoding.EncodeToString(b)
 
 	slc, err := c.Auth2Fms(ctx, authToken, partialKey)
 	if err != nil {
-		return "", err
+		return AuthInfo{}, err
 	}
 	if err := verifyAuth2FmsResponse(slc); err != nil {
-		return "", err
+		return AuthInfo{}, err
+	}
+
+	return AuthInfo{
+		AuthToken: authToken,
+		AreaID:    strings.TrimSpace(slc[0]),
+	}, nil
+}
+
+// reauthorize runs the auth flow again for callWithAuthTokenHeader.
+var reauthorize = func(ctx context.Context, c *Client) error {
+	_, err := c.AuthorizeToken(ctx)
+	return err
+}
+
+// callWithAuthTokenHeader sends a request with the auth token.
+// If the token is rejected with 401 or 403, or radiko responds
+// with the body telling the token is stale, and auto re-auth is enabled,
+// the auth flow is run once and the request is sent again.
+// The concurrent rejections share a single run of the auth flow.
+// The body of params must be nil to retry the request.
+func (c *Client) callWithAuthTokenHeader(ctx context.Context, verb, apiEndpoint string, params *Params) (*http.Response, error) {
+	return c.callURLWithAuthTokenHeader(ctx, verb, c.endpointURL(apiEndpoint), params)
+}
+
+// callURLWithAuthTokenHeader is like callWithAuthTokenHeader,
+// but sends the request to the absolute url as newRequestToURL does.
+func (c *Client) callURLWithAuthTokenHeader(ctx context.Context, verb string, u *url.URL, params *Params) (*http.Response, error) {
+	params.setAuthToken = true
+
+	req, err := c.newRequestToURL(ctx, verb, u, params)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if !c.autoReauth || params.body != nil {
+		return resp, nil
+	}
+	if !isAuthRejected(resp) {
+		stale, err := isTokenStale(resp)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if !stale {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+
+	if err = c.reauthorizeOnce(ctx, req.Header.Get(radikoAuthTokenHeader), resp.StatusCode); err != nil {
+		return nil, err
+	}
+
+	req, err = c.newRequestToURL(ctx, verb, u, params)
+	if err != nil {
+		return nil, err
 	}
+	return c.Do(req)
+}
+
+// reauthorizeOnce runs reauthorize for the rejected token.
+// The concurrent calls wait for the running one, and the auth flow is skipped
+// if the token has already been changed since it was sent.
+func (c *Client) reauthorizeOnce(ctx context.Context, rejected string, status int) error {
+	c.reauthMu.Lock()
+	defer c.reauthMu.Unlock()
 
-	c.setAuthTokenHeader(authToken)
-	return authToken, nil
+	if c.AuthToken() != rejected {
+		return nil
+	}
+	c.logf("radiko: auth token is rejected with %d, reauthorizing", status)
+	return reauthorize(ctx, c)
+}
+
+// isAuthRejected reports whether the response rejects the auth token.
+func isAuthRejected(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusUnauthorized ||
+		resp.StatusCode == http.StatusForbidden
+}
+
+// staleTokenBodies are the bodies which radiko responds with
+// instead of 401 when the auth token is stale.
+var staleTokenBodies = []string{"expired", "invalid token"}
+
+// isTokenStale reports whether the body of resp tells the auth token is stale.
+// The beginning of the body is peeked, and resp.Body is replaced
+// so that it can still be read from the start.
+func isTokenStale(resp *http.Response) (bool, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxAPIErrorBody))
+	if err != nil {
+		return false, err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+
+	body := strings.ToLower(strings.TrimSpace(string(b)))
+	for _, stale := range staleTokenBodies {
+		if body == stale {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // Auth1Fms returns authToken, keyLength, keyOffset and error.
@@ -53,10 +200,9 @@ func (c *Client) Auth1Fms(ctx context.Context) (string, int64, int64, error) {
 
 	req, err := c.newRequest(ctx, "POST", apiEndpoint, &Params{
 		header: map[string]string{
-			radikoAppHeader:        radikoApp,
-			radikoAppVersionHeader: radikoAppVersion,
-			radikoUserHeader:       radikoUser,
-			radikoDeviceHeader:     radikoDevice,
+			radikoAppHeader:    radikoApp,
+			radikoUserHeader:   radikoUser,
+			radikoDeviceHeader: radikoDevice,
 		},
 	})
 	if err != nil {
@@ -69,18 +215,30 @@ func (c *Client) Auth1Fms(ctx context.Context) (string, int64, int64, error) {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, 0, readAPIError(resp)
+	}
+
 	authToken := resp.Header.Get(radikoAuthTokenHeader)
 	keyLength := resp.Header.Get(radikoKeyLentghHeader)
 	keyOffset := resp.Header.Get(radikoKeyOffsetHeader)
 
 	length, err := strconv.ParseInt(keyLength, 10, 64)
 	if err != nil {
-		return "", 0, 0, err
+		return "", 0, 0, fmt.Errorf("%w: invalid key length: %v", ErrAuthFailed, err)
 	}
 	offset, err := strconv.ParseInt(keyOffset, 10, 64)
 	if err != nil {
-		return "", 0, 0, err
+		return "", 0, 0, fmt.Errorf("%w: invalid key offset: %v", ErrAuthFailed, err)
+	}
+
+	c.mu.Lock()
+	c.authDebug = AuthDebug{
+		KeyLength:   length,
+		KeyOffset:   offset,
+		TokenPrefix: redactToken(authToken),
 	}
+	c.mu.Unlock()
 
 	return authToken, length, offset, err
 }
@@ -92,7 +250,6 @@ func (c *Client) Auth2Fms(ctx context.Context, authToken, partialKey string) ([]
 	req, err := c.newRequest(ctx, "POST", apiEndpoint, &Params{
 		header: map[string]string{
 			radikoAppHeader:        radikoApp,
-			radikoAppVersionHeader: radikoAppVersion,
 			radikoUserHeader:       radikoUser,
 			radikoDeviceHeader:     radikoDevice,
 			radikoAuthTokenHeader:  authToken,
@@ -109,23 +266,62 @@ func (c *Client) Auth2Fms(ctx context.Context, authToken, partialKey string) ([]
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, readAPIError(resp)
+	}
+
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
 	s := strings.Split(string(b), ",")
+
+	c.mu.Lock()
+	c.authDebug.AreaID = strings.TrimSpace(s[0])
+	c.mu.Unlock()
+
 	return s, nil
 }
 
 func verifyAuth2FmsResponse(slc []string) error {
 	if len(slc) == 0 {
-		return errors.New("missing token")
+		return fmt.Errorf("%w: missing token", ErrAuthFailed)
 	}
 	s := strings.TrimSpace(slc[0])
 	if !strings.HasPrefix(s, "JP") {
-		return fmt.Errorf("invalid token: %s", s)
+		return fmt.Errorf("%w: invalid token: %s", ErrAuthFailed, s)
 	}
 
 	return nil
 }
+
+// AuthDebug is the diagnostics of the last auth handshake.
+// It does not include the full auth_token.
+type AuthDebug struct {
+	// KeyLength is the length of the partial key.
+	KeyLength int64
+	// KeyOffset is the offset of the partial key.
+	KeyOffset int64
+	// TokenPrefix is the redacted auth_token.
+	TokenPrefix string
+	// AreaID is the area returned by auth2_fms.
+	AreaID string
+}
+
+// AuthDebugInfo returns the diagnostics of the last auth handshake.
+func (c *Client) AuthDebugInfo() AuthDebug {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.authDebug
+}
+
+// redactToken returns the first characters of the token,
+// masking the rest.
+func redactToken(token string) string {
+	const visible = 4
+	if len(token) <= visible*2 {
+		return strings.Repeat("*", len(token))
+	}
+	return token[:visible] + strings.Repeat("*", len(token)-visible)
+}
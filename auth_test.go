@@ -1,11 +1,19 @@
 package radiko
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestAuthorizeToken(t *testing.T) {
@@ -97,8 +105,8 @@ func TestVerifyAuth2FmsResponse(t *testing.T) {
 	for _, c := range cases {
 		err := verifyAuth2FmsResponse(c.slc)
 		if c.expectedErr {
-			if err == nil {
-				t.Error("Should detect an error.")
+			if !errors.Is(err, ErrAuthFailed) {
+				t.Errorf("expected %s, but %v", ErrAuthFailed, err)
 			}
 			continue
 		}
@@ -107,3 +115,221 @@ func TestVerifyAuth2FmsResponse(t *testing.T) {
 		}
 	}
 }
+
+func TestAuthorize(t *testing.T) {
+	defer func(f func() ([]byte, error)) { loadAuthKey = f }(loadAuthKey)
+	loadAuthKey = func() ([]byte, error) {
+		return bytes.Repeat([]byte("k"), 64), nil
+	}
+
+	var partialKey string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/api/auth1_fms":
+			w.Header().Set(radikoAuthTokenHeader, "token")
+			w.Header().Set(radikoKeyLentghHeader, "16")
+			w.Header().Set(radikoKeyOffsetHeader, "8")
+		case "/v2/api/auth2_fms":
+			partialKey = r.Header.Get(radikoPartialKeyHeader)
+			w.Write([]byte("\r\n\r\nJP27,大阪府,osaka Japan"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+
+	info, err := c.Authorize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (AuthInfo{AuthToken: "token", AreaID: "JP27"}); expected != info {
+		t.Errorf("expected %v, but %v", expected, info)
+	}
+	if expected := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("k"), 16)); expected != partialKey {
+		t.Errorf("expected %s, but %s", expected, partialKey)
+	}
+	if c.AuthToken() != "token" || c.AreaID() != "JP27" {
+		t.Errorf("AuthInfo is not set: %s, %s", c.AuthToken(), c.AreaID())
+	}
+
+	c.SetAuthInfo(AuthInfo{AuthToken: "saved"})
+	if c.AuthToken() != "saved" || c.AreaID() != "JP27" {
+		t.Errorf("AuthInfo is not restored: %s, %s", c.AuthToken(), c.AreaID())
+	}
+}
+
+func TestAuthDebugInfo(t *testing.T) {
+	const authToken = "abcdefghijklmnopqrstuvwxyz"
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/api/auth1_fms":
+			w.Header().Set(radikoAuthTokenHeader, authToken)
+			w.Header().Set(radikoKeyLentghHeader, "16")
+			w.Header().Set(radikoKeyOffsetHeader, "2048")
+		case "/v2/api/auth2_fms":
+			w.Write([]byte("\r\n\r\nJP13,東京都,tokyo Japan"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+
+	ctx := context.Background()
+	token, _, _, err := c.Auth1Fms(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Auth2Fms(ctx, token, "partial_key"); err != nil {
+		t.Fatal(err)
+	}
+
+	info := c.AuthDebugInfo()
+	if info.KeyLength != 16 || info.KeyOffset != 2048 {
+		t.Errorf("Length: %d, Offset: %d", info.KeyLength, info.KeyOffset)
+	}
+	if info.AreaID != "JP13" {
+		t.Errorf("expected JP13, but %s", info.AreaID)
+	}
+	if !strings.HasPrefix(info.TokenPrefix, "abcd") || strings.Contains(info.TokenPrefix, authToken[4:]) {
+		t.Errorf("token is not redacted: %s", info.TokenPrefix)
+	}
+}
+
+func TestCallWithAuthTokenHeader_Reauth(t *testing.T) {
+	var tokens []string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(radikoAuthTokenHeader)
+		tokens = append(tokens, token)
+		if token != "fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	defer closer()
+	c.setAuthTokenHeader("expired")
+
+	defer func(f func(context.Context, *Client) error) { reauthorize = f }(reauthorize)
+	reauthorize = func(ctx context.Context, c *Client) error {
+		c.setAuthTokenHeader("fresh")
+		return nil
+	}
+
+	// Disabled
+	resp, err := c.callWithAuthTokenHeader(context.Background(), "POST", "", &Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected %d, but %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	// Enabled
+	if err = WithAutoReauth(true)(c); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = c.callWithAuthTokenHeader(context.Background(), "POST", "", &Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %d, but %d", http.StatusOK, resp.StatusCode)
+	}
+
+	expected := []string{"expired", "expired", "fresh"}
+	if strings.Join(expected, ",") != strings.Join(tokens, ",") {
+		t.Errorf("expected %v, but %v", expected, tokens)
+	}
+}
+
+func TestCallWithAuthTokenHeader_ConcurrentReauth(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(radikoAuthTokenHeader) != "fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	defer closer()
+	c.setAuthTokenHeader("expired")
+	c.autoReauth = true
+
+	var calls int32
+	defer func(f func(context.Context, *Client) error) { reauthorize = f }(reauthorize)
+	reauthorize = func(ctx context.Context, c *Client) error {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		c.setAuthTokenHeader("fresh")
+		return nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.callWithAuthTokenHeader(context.Background(), "GET", "", &Params{})
+			if err != nil {
+				errs <- err
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				errs <- errors.New(resp.Status)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if expected, actual := int32(1), atomic.LoadInt32(&calls); expected != actual {
+		t.Errorf("expected %d, but %d", expected, actual)
+	}
+}
+
+func TestCallWithAuthTokenHeader_StaleToken(t *testing.T) {
+	var tokens []string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(radikoAuthTokenHeader)
+		tokens = append(tokens, token)
+		if token != "fresh" {
+			w.Write([]byte("expired\n"))
+			return
+		}
+		w.Write([]byte("#EXTM3U\n"))
+	}))
+	defer closer()
+	c.setAuthTokenHeader("stale")
+	c.autoReauth = true
+
+	defer func(f func(context.Context, *Client) error) { reauthorize = f }(reauthorize)
+	reauthorize = func(ctx context.Context, c *Client) error {
+		c.setAuthTokenHeader("fresh")
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.callWithAuthTokenHeader(context.Background(), "POST", "", &Params{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "#EXTM3U\n"; expected != string(b) {
+			t.Errorf("expected %s, but %s", expected, b)
+		}
+	}
+
+	expected := []string{"stale", "fresh", "fresh"}
+	if strings.Join(expected, ",") != strings.Join(tokens, ",") {
+		t.Errorf("expected %v, but %v", expected, tokens)
+	}
+}
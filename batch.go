@@ -0,0 +1,89 @@
+package radiko
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultMaxConcurrency = 4
+
+var maxConcurrency int32 = defaultMaxConcurrency
+
+// SetMaxConcurrency overrides the max number of concurrent requests
+// sent by the methods that fetch multiple resources.
+// If n is not positive, the default is used.
+// It applies to the clients without WithMaxConcurrency.
+func SetMaxConcurrency(n int) {
+	if n <= 0 {
+		n = defaultMaxConcurrency
+	}
+	atomic.StoreInt32(&maxConcurrency, int32(n))
+}
+
+// concurrency returns the max number of concurrent requests of the Client.
+func (c *Client) concurrency() int {
+	if c.maxConcurrency > 0 {
+		return c.maxConcurrency
+	}
+	return int(atomic.LoadInt32(&maxConcurrency))
+}
+
+// parallel calls fn with 0 to n-1 concurrently,
+// up to the concurrency of the Client at a time.
+// ctx is passed to fn, and no more fn is called after ctx is done.
+// If fn returns an error, ctx passed to the others is canceled.
+// It returns the error of the smallest index if any,
+// except for the cancellations caused by the error.
+func (c *Client) parallel(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, c.concurrency())
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := parent.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+			if errs[i] = fn(ctx, i); errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var first error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if first == nil {
+			first = err
+		}
+		// The cancellation is caused by the error of another index
+		// unless the parent is done.
+		if errors.Is(err, context.Canceled) && parent.Err() == nil {
+			continue
+		}
+		return err
+	}
+	return first
+}
@@ -0,0 +1,134 @@
+package radiko
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParallel(t *testing.T) {
+	c := &Client{maxConcurrency: 2}
+
+	var (
+		mu            sync.Mutex
+		running, peak int
+	)
+	results := make([]int, 10)
+	err := c.parallel(context.Background(), len(results), func(_ context.Context, i int) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+		results[i] = i * i
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak > 2 {
+		t.Errorf("expected at most 2 workers, but %d", peak)
+	}
+	for i, r := range results {
+		if r != i*i {
+			t.Errorf("expected %d, but %d", i*i, r)
+		}
+	}
+}
+
+func TestParallel_Error(t *testing.T) {
+	expected := errors.New("first")
+	err := (&Client{}).parallel(context.Background(), 3, func(_ context.Context, i int) error {
+		switch i {
+		case 1:
+			return expected
+		case 2:
+			return errors.New("second")
+		}
+		return nil
+	})
+	if err != expected {
+		t.Errorf("expected %v, but %v", expected, err)
+	}
+}
+
+func TestParallel_CancelOnError(t *testing.T) {
+	expected := errors.New("failed")
+	err := (&Client{}).parallel(context.Background(), 3, func(ctx context.Context, i int) error {
+		if i == 2 {
+			return expected
+		}
+		// The others wait until the error cancels them.
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != expected {
+		t.Errorf("expected %v, but %v", expected, err)
+	}
+}
+
+func TestSetMaxConcurrency(t *testing.T) {
+	c := &Client{}
+
+	SetMaxConcurrency(8)
+	if expected := 8; expected != c.concurrency() {
+		t.Errorf("expected %d, but %d", expected, c.concurrency())
+	}
+
+	SetMaxConcurrency(0)
+	if expected := defaultMaxConcurrency; expected != c.concurrency() {
+		t.Errorf("expected %d, but %d", expected, c.concurrency())
+	}
+}
+
+func TestWithMaxConcurrency(t *testing.T) {
+	c := &Client{}
+	if err := WithMaxConcurrency(8)(c); err != nil {
+		t.Fatal(err)
+	}
+	if expected := 8; expected != c.concurrency() {
+		t.Errorf("expected %d, but %d", expected, c.concurrency())
+	}
+
+	if err := WithMaxConcurrency(0)(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestParallel_Cancel(t *testing.T) {
+	c := &Client{maxConcurrency: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	err := c.parallel(ctx, 10, func(ctx context.Context, i int) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		if i == 1 {
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expected := 2; calls != expected {
+		t.Errorf("expected %d calls, but %d", expected, calls)
+	}
+}
@@ -0,0 +1,156 @@
+package radiko
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+// cache holds the decoded responses keyed by the API endpoint.
+// The nil cache caches nothing.
+type cache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value interface{}
+	// expires is zero if the entry never expires.
+	expires time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (c *cache) get(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.expires.IsZero() && !time.Now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// set caches the value for the ttl.
+// If permanent is true, the value never expires.
+func (c *cache) set(key string, value interface{}, permanent bool) {
+	if c == nil {
+		return
+	}
+
+	e := cacheEntry{value: value}
+	if !permanent {
+		e.expires = time.Now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	c.entries[key] = e
+	c.mu.Unlock()
+}
+
+func (c *cache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.entries = make(map[string]cacheEntry)
+	c.mu.Unlock()
+}
+
+// ClearCache flushes the cached station lists, areas and schedules.
+func (c *Client) ClearCache() {
+	c.mu.Lock()
+	c.stationDirectory = nil
+	c.areas = nil
+	c.mu.Unlock()
+
+	c.cache.clear()
+}
+
+// isPastProgramsDate reports whether the schedule of date is complete,
+// that is, the date is before today in the radiko's day boundary.
+func isPastProgramsDate(date time.Time) bool {
+	return util.ProgramsDate(date) < util.ProgramsDate(time.Now())
+}
+
+// clone returns a deep copy of the stations.
+func (s Stations) clone() Stations {
+	if s == nil {
+		return nil
+	}
+	cloned := make(Stations, len(s))
+	for i, station := range s {
+		cloned[i] = station.clone()
+	}
+	return cloned
+}
+
+func (s Station) clone() Station {
+	s.Scd.Progs = s.Scd.Progs.clone()
+	s.Progs = s.Progs.clone()
+	s.Logos = append([]Logo(nil), s.Logos...)
+	return s
+}
+
+func (p Progs) clone() Progs {
+	if p.Progs == nil {
+		return p
+	}
+	progs := make([]Prog, len(p.Progs))
+	for i, prog := range p.Progs {
+		progs[i] = prog.clone()
+	}
+	p.Progs = progs
+	return p
+}
+
+func (p Prog) clone() Prog {
+	if p.Images != nil {
+		images := make(Images, len(p.Images))
+		for k, v := range p.Images {
+			images[k] = v
+		}
+		p.Images = images
+	}
+	return p
+}
+
+// clone returns a deep copy of the radio stations.
+func (rs RadioStations) clone() RadioStations {
+	if rs == nil {
+		return nil
+	}
+	cloned := make(RadioStations, len(rs))
+	for i, s := range rs {
+		s.AreaIDs = append([]string(nil), s.AreaIDs...)
+		s.Logos = append([]Logo(nil), s.Logos...)
+		s.StreamURLs = append([]StreamURL(nil), s.StreamURLs...)
+		cloned[i] = s
+	}
+	return cloned
+}
+
+// clone returns a deep copy of the stations data.
+func (d *stationsData) clone() *stationsData {
+	cloned := *d
+	cloned.XMLStations.Stations = d.XMLStations.Stations.clone()
+	return &cloned
+}
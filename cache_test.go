@@ -0,0 +1,126 @@
+package radiko
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCacheTestClient(t *testing.T) (*Client, *int32, func()) {
+	var requests int32
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if strings.HasPrefix(r.URL.Path, "/v3/station/list/") {
+			http.ServeFile(w, r, filepath.Join(testdataDir, "station_list.xml"))
+			return
+		}
+		http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+	}))
+	if err := WithCache(time.Hour)(c); err != nil {
+		t.Fatal(err)
+	}
+	return c, &requests, closer
+}
+
+func TestWithCache_RadioStations(t *testing.T) {
+	c, requests, closer := newCacheTestClient(t)
+	defer closer()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetRadioStations(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected := int32(1); atomic.LoadInt32(requests) != expected {
+		t.Errorf("expected %d, but %d", expected, *requests)
+	}
+
+	c.ClearCache()
+	if _, err := c.GetRadioStations(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if expected := int32(2); atomic.LoadInt32(requests) != expected {
+		t.Errorf("expected %d, but %d", expected, *requests)
+	}
+}
+
+func TestWithCache_Expired(t *testing.T) {
+	c, requests, closer := newCacheTestClient(t)
+	defer closer()
+
+	if _, err := c.GetStations(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for k, e := range c.cache.entries {
+		if e.expires.IsZero() {
+			t.Errorf("%s should expire", k)
+		}
+		e.expires = time.Now().Add(-time.Second)
+		c.cache.entries[k] = e
+	}
+
+	if _, err := c.GetStations(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if expected := int32(2); atomic.LoadInt32(requests) != expected {
+		t.Errorf("expected %d, but %d", expected, *requests)
+	}
+}
+
+func TestWithCache_PastSchedule(t *testing.T) {
+	c, requests, closer := newCacheTestClient(t)
+	defer closer()
+
+	date := time.Now().AddDate(0, 0, -3)
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetStations(context.Background(), date); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected := int32(1); atomic.LoadInt32(requests) != expected {
+		t.Errorf("expected %d, but %d", expected, *requests)
+	}
+	for k, e := range c.cache.entries {
+		if !e.expires.IsZero() {
+			t.Errorf("%s should not expire", k)
+		}
+	}
+}
+
+func TestWithCache_Copy(t *testing.T) {
+	c, _, closer := newCacheTestClient(t)
+	defer closer()
+
+	date := time.Now().AddDate(0, 0, -3)
+	stations, err := c.GetStations(context.Background(), date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) == 0 || len(stations[0].Progs.Progs) == 0 {
+		t.Fatal("expected the programs")
+	}
+	expected := stations[0].Progs.Progs[0].Title
+	stations[0].Progs.Progs[0].Title = "modified"
+	stations[0] = Station{}
+
+	stations, err = c.GetStations(context.Background(), date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := stations[0].Progs.Progs[0].Title; actual != expected {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+}
+
+func TestWithCache_Invalid(t *testing.T) {
+	c, closer := newTestClient(t, http.NotFoundHandler())
+	defer closer()
+
+	if err := WithCache(0)(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
@@ -1,16 +1,21 @@
 package radiko
 
 import (
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 	"path"
 	"runtime"
+	"sync"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -49,10 +54,40 @@ type Client struct {
 	httpClient      *http.Client
 	authTokenHeader string
 	areaID          string
+	userAgent       string
+	appVersion      string
+
+	logger             *log.Logger
+	timeshiftTolerance time.Duration
+	autoReauth         bool
+	retryAttempts      int
+	retryBaseDelay     time.Duration
+	limiter            *rate.Limiter
+	requestLogger      RequestLogger
+	requestTimeout     time.Duration
+	cache              *cache
+	normalizeText      bool
+	normalizeNFKC      bool
+	listedStreamURLs   bool
+	maxConcurrency     int
+
+	// transport is the transport created by WithTransportTuning.
+	transport *http.Transport
+
+	// reauthMu serializes the re-auths of callWithAuthTokenHeader.
+	reauthMu sync.Mutex
+
+	mu               sync.Mutex
+	logos            map[string]string
+	stationDirectory map[string]RadioStations
+	areas            []Area
+	authDebug        AuthDebug
 }
 
 // New returns a new Client struct.
-func New(authToken string) (*Client, error) {
+// The options are applied in order.
+// The areaID is detected from the current IP unless WithAreaID is given.
+func New(authToken string, opts ...Option) (*Client, error) {
 	if httpClient == nil {
 		return nil, errors.New("httpClient is nil")
 	}
@@ -68,17 +103,51 @@ func New(authToken string) (*Client, error) {
 		return nil, err
 	}
 
-	areaID, err := AreaID()
-	if err != nil {
-		return nil, err
-	}
-
-	return &Client{
+	c := &Client{
 		URL:             parsedURL,
 		httpClient:      httpClient,
 		authTokenHeader: authToken,
-		areaID:          areaID,
-	}, nil
+		autoReauth:      true,
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.AreaID() == "" {
+		areaID, err := AreaID()
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.areaID = areaID
+		c.mu.Unlock()
+	}
+	return c, nil
+}
+
+// NewClient returns a new Client configured by the options.
+// The auth token is empty until the client is authorized.
+// If WithHTTPClient is not given, the default HTTP client is used.
+func NewClient(opts ...Option) (*Client, error) {
+	return New("", opts...)
+}
+
+// Close releases the resources held by the Client.
+// It flushes the caches and closes the idle connections
+// of the transport created by WithTransportTuning.
+// The shared transports, like http.DefaultTransport, are left open.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.logos = nil
+	c.mu.Unlock()
+	c.ClearCache()
+
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
+	}
+	return nil
 }
 
 // Jar returns the cookieJar.
@@ -93,26 +162,112 @@ func (c *Client) SetJar(jar *cookiejar.Jar) {
 
 // AreaID returns the areaID.
 func (c *Client) AreaID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.areaID
 }
 
+type areaContextKey struct{}
+
+// WithAreaContext returns a copy of ctx which overrides the Client's area
+// for the calls with it, like GetStations and GetRadioStations.
+// The area given explicitly to a method like GetStationsForArea
+// takes precedence over the context, which takes precedence over the Client's area.
+// The checks of the area which the auth token is for,
+// like StreamPlaylistM3U8 does, still use the Client's area.
+func WithAreaContext(ctx context.Context, areaID string) context.Context {
+	return context.WithValue(ctx, areaContextKey{}, areaID)
+}
+
+// contextAreaID returns the area set by WithAreaContext,
+// or the Client's area if ctx has none.
+func (c *Client) contextAreaID(ctx context.Context) string {
+	if areaID, ok := ctx.Value(areaContextKey{}).(string); ok && areaID != "" {
+		return areaID
+	}
+	return c.AreaID()
+}
+
 // SetAreaID sets the areaID.
-func (c *Client) SetAreaID(areaID string) {
+// If the areaID is not one of JP1 to JP47, it returns ErrInvalidAreaID
+// and the Client's area is not changed.
+func (c *Client) SetAreaID(areaID string) error {
+	if err := ValidateAreaID(areaID); err != nil {
+		return err
+	}
+	c.mu.Lock()
 	c.areaID = areaID
+	c.mu.Unlock()
+	return nil
 }
 
 // AuthToken returns the authtoken.
 func (c *Client) AuthToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.authTokenHeader
 }
 
+// SetLogger sets the logger used for warnings.
+// If the logger is nil, nothing is logged.
+func (c *Client) SetLogger(logger *log.Logger) {
+	c.logger = logger
+}
+
+// SetTimeshiftTolerance sets the tolerance used when a start time
+// does not match any program exactly.
+// If the tolerance is positive, the start time is clamped to the nearest
+// program that starts within the tolerance.
+func (c *Client) SetTimeshiftTolerance(d time.Duration) {
+	c.timeshiftTolerance = d
+}
+
+// getUserAgent returns the User-Agent set by WithUserAgent,
+// or the default one.
+func (c *Client) getUserAgent() string {
+	if c.userAgent != "" {
+		return c.userAgent
+	}
+	return userAgent
+}
+
+// getAppVersion returns the app version set by WithAppVersion,
+// or the default one.
+func (c *Client) getAppVersion() string {
+	if c.appVersion != "" {
+		return c.appVersion
+	}
+	return radikoAppVersion
+}
+
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+	}
+}
+
 func (c *Client) setAuthTokenHeader(authToken string) {
+	c.mu.Lock()
 	c.authTokenHeader = authToken
+	c.mu.Unlock()
 }
 
 func (c *Client) newRequest(ctx context.Context, verb, apiEndpoint string, params *Params) (*http.Request, error) {
+	return c.newRequestToURL(ctx, verb, c.endpointURL(apiEndpoint), params)
+}
+
+// endpointURL returns the url of apiEndpoint under the Client's URL.
+func (c *Client) endpointURL(apiEndpoint string) *url.URL {
 	u := *c.URL
 	u.Path = path.Join(c.URL.Path, apiEndpoint)
+	return &u
+}
+
+// newRequestToURL is like newRequest, but sends the request to the absolute url
+// instead of an endpoint under the Client's URL.
+// The auth token may be set, so u must be on a host of radiko.
+func (c *Client) newRequestToURL(ctx context.Context, verb string, target *url.URL, params *Params) (*http.Request, error) {
+	u := *target
 
 	// Add query parameters
 	urlQuery := u.Query()
@@ -136,7 +291,11 @@ func (c *Client) newRequest(ctx context.Context, verb, apiEndpoint string, param
 	for k, v := range params.header {
 		req.Header.Set(k, v)
 	}
-	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("User-Agent", c.getUserAgent())
+	req.Header.Set(radikoAppVersionHeader, c.getAppVersion())
+	// Request gzip explicitly, since the transport of WithHTTPClient
+	// may not do it. The response is decompressed in send.
+	req.Header.Set("Accept-Encoding", "gzip")
 	// For backwards compatibility with HTTP/1.0
 	// https://tools.ietf.org/html/rfc7234#page-29
 	req.Header.Set("pragma", "no-cache")
@@ -150,7 +309,106 @@ func (c *Client) newRequest(ctx context.Context, verb, apiEndpoint string, param
 
 // Do executes an API request.
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	return c.httpClient.Do(req)
+	if c.retryAttempts > 1 && isIdempotent(req.Method) {
+		return c.doWithRetry(req)
+	}
+	return c.send(req)
+}
+
+// send sends req once. If the rate limit is set,
+// it waits for the limiter until the request context is done.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	if c.requestLogger == nil {
+		return decompress(c.doWithTimeout(req))
+	}
+
+	start := time.Now()
+	resp, err := decompress(c.doWithTimeout(req))
+
+	var status int
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	u := *req.URL
+	u.User = nil
+	c.requestLogger(req.Context(), req.Method, u.String(), status, time.Since(start), err)
+	return resp, err
+}
+
+// doWithTimeout sends req with the deadline set by WithRequestTimeout.
+// The deadline of the request context is kept if it is earlier.
+// The deadline covers reading the body, and it is released when the body is closed.
+func (c *Client) doWithTimeout(req *http.Request) (*http.Response, error) {
+	if c.requestTimeout <= 0 {
+		return c.httpClient.Do(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), c.requestTimeout)
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody cancels the request context when the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// decompress replaces the gzip-encoded body of resp with the decompressed one.
+// The transport decompresses it by itself unless Accept-Encoding is set.
+func decompress(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil || resp.Uncompressed || resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp, err
+	}
+
+	resp.Body = &gzipBody{body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// gzipBody decompresses the body from the first Read,
+// so the empty body of HEAD or 204 is not read.
+// Close closes both the gzip reader and the body.
+type gzipBody struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+	err  error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil && b.err == nil {
+		b.zr, b.err = gzip.NewReader(b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	if b.zr != nil {
+		b.zr.Close()
+	}
+	return b.body.Close()
 }
 
 // Params is the list of options to pass to the request.
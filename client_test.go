@@ -1,12 +1,20 @@
 package radiko
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
 )
 
 func TestNew(t *testing.T) {
@@ -16,6 +24,20 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewClient_WithHTTPClient(t *testing.T) {
+	hc := &http.Client{Timeout: time.Second}
+	c, err := NewClient(WithHTTPClient(hc), WithAreaID(areaIDTokyo))
+	if err != nil {
+		t.Fatalf("Failed to construct client: %s", err)
+	}
+	if c.httpClient.Timeout != time.Second {
+		t.Errorf("expected %s, but %s", time.Second, c.httpClient.Timeout)
+	}
+	if c.AuthToken() != "" {
+		t.Errorf("expected an empty auth token, but %s", c.AuthToken())
+	}
+}
+
 func TestNew_EmptyHTTPClient(t *testing.T) {
 	var c *http.Client
 
@@ -220,3 +242,141 @@ func TestAPIPath(t *testing.T) {
 		t.Errorf("invalid apiEndpoint: %s", apiEndpoint)
 	}
 }
+
+func TestClient_Close(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	c, closer := newTestClient(t, serveTestdata("logo.png"))
+	if err := WithTransportTuning(10, 10, time.Minute)(c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.StationLogoDataURL(context.Background(), "TBS"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Error(err)
+	}
+	closer()
+
+	if len(c.logos) != 0 {
+		t.Errorf("cache is not flushed: %v", c.logos)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutine leak: before %d, after %d", before, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type idleCountingTransport struct {
+	http.RoundTripper
+	closed int
+}
+
+func (tr *idleCountingTransport) CloseIdleConnections() {
+	tr.closed++
+}
+
+func TestClient_Close_SharedTransport(t *testing.T) {
+	tr := &idleCountingTransport{RoundTripper: http.DefaultTransport}
+	c, err := NewClient(WithHTTPClient(&http.Client{Transport: tr}), WithAreaID(areaIDTokyo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Error(err)
+	}
+	if tr.closed != 0 {
+		t.Errorf("expected %d, but %d", 0, tr.closed)
+	}
+}
+
+func TestWithAreaContext(t *testing.T) {
+	var paths []string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+	}))
+	defer closer()
+
+	date := time.Date(2016, 11, 12, 12, 0, 0, 0, util.Location())
+	ctx := context.Background()
+	areaCtx := WithAreaContext(ctx, "JP27")
+	if _, err := c.GetStations(ctx, date); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetStations(areaCtx, date); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetStationsForArea(areaCtx, "JP40", date); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"/v3/program/date/20161112/JP13.xml",
+		"/v3/program/date/20161112/JP27.xml",
+		"/v3/program/date/20161112/JP40.xml",
+	}
+	if strings.Join(expected, ",") != strings.Join(paths, ",") {
+		t.Errorf("expected %v, but %v", expected, paths)
+	}
+	if expected := "JP13"; c.AreaID() != expected {
+		t.Errorf("expected %s, but %s", expected, c.AreaID())
+	}
+}
+
+func TestClient_SetAreaID_Invalid(t *testing.T) {
+	c, closer := newTestClient(t, http.NotFoundHandler())
+	defer closer()
+
+	for _, areaID := range []string{"", "JP0", "JP48", "jp13", "OUT"} {
+		if err := c.SetAreaID(areaID); !errors.Is(err, ErrInvalidAreaID) {
+			t.Errorf("%q: expected %s, but %v", areaID, ErrInvalidAreaID, err)
+		}
+	}
+	if expected := areaIDTokyo; c.AreaID() != expected {
+		t.Errorf("expected %s, but %s", expected, c.AreaID())
+	}
+
+	if err := c.SetAreaID("JP47"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "JP47"; c.AreaID() != expected {
+		t.Errorf("expected %s, but %s", expected, c.AreaID())
+	}
+}
+
+func TestClient_Gzip(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join(testdataDir, "programs.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(b)
+	zw.Close()
+
+	var acceptEncoding string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gz.Bytes())
+	}))
+	defer closer()
+
+	stations, err := c.GetStations(context.Background(), programsFixtureDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 2; len(stations) != expected {
+		t.Errorf("expected %d, but %d", expected, len(stations))
+	}
+	if expected := "gzip"; acceptEncoding != expected {
+		t.Errorf("expected %s, but %s", expected, acceptEncoding)
+	}
+}
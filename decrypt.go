@@ -0,0 +1,124 @@
+package radiko
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/chikulla/go-radiko/internal/m3u8"
+)
+
+const keyMethodAES128 = "AES-128"
+
+// NewDecryptingReader returns a reader of the segment read from r
+// decrypted with AES-128-CBC and PKCS#7 padding.
+// The whole segment is read from r in advance.
+func NewDecryptingReader(r io.Reader, key, iv []byte) (io.Reader, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b, err = decryptAES128(b, key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+func decryptAES128(b, key, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, errors.New("invalid IV length")
+	}
+	if len(b) == 0 || len(b)%block.BlockSize() != 0 {
+		return nil, errors.New("invalid encrypted segment length")
+	}
+
+	out := make([]byte, len(b))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, b)
+
+	pad := int(out[len(out)-1])
+	if pad == 0 || pad > block.BlockSize() {
+		return nil, errors.New("invalid padding")
+	}
+	for _, p := range out[len(out)-pad:] {
+		if int(p) != pad {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return out[:len(out)-pad], nil
+}
+
+// decryptSegment returns b decrypted with the segment's key.
+// If the segment is not encrypted, b is returned untouched.
+func (c *Client) decryptSegment(ctx context.Context, b []byte, s m3u8.Segment, d *timeshiftDownload) ([]byte, error) {
+	if s.Key == nil {
+		return b, nil
+	}
+	if s.Key.Method != keyMethodAES128 {
+		return nil, fmt.Errorf("unsupported key method: %s", s.Key.Method)
+	}
+
+	key, err := d.key(ctx, c, s.Key.URI)
+	if err != nil {
+		return nil, err
+	}
+	return decryptAES128(b, key, s.Key.IV)
+}
+
+// key returns the key of the uri. The keys are cached during the download.
+func (d *timeshiftDownload) key(ctx context.Context, c *Client, uri string) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if key, ok := d.keys[uri]; ok {
+		return key, nil
+	}
+	key, err := c.getKey(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if d.keys == nil {
+		d.keys = make(map[string][]byte)
+	}
+	d.keys[uri] = key
+	return key, nil
+}
+
+// getKey fetches the key with the auth token.
+func (c *Client) getKey(ctx context.Context, uri string) ([]byte, error) {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", c.getUserAgent())
+	req.Header.Set(radikoAuthTokenHeader, c.AuthToken())
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, b)
+	}
+	if len(b) != 16 {
+		return nil, fmt.Errorf("invalid key length: %d", len(b))
+	}
+	return b, nil
+}
@@ -0,0 +1,127 @@
+package radiko
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+var (
+	testKey = []byte("0123456789abcdef")
+	testIV  = []byte("fedcba9876543210")
+)
+
+func encryptAES128(t *testing.T, b, key, iv []byte) []byte {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pad := block.BlockSize() - len(b)%block.BlockSize()
+	b = append(b, bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+	out := make([]byte, len(b))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, b)
+	return out
+}
+
+func TestNewDecryptingReader(t *testing.T) {
+	encrypted := encryptAES128(t, []byte("segment"), testKey, testIV)
+
+	r, err := NewDecryptingReader(bytes.NewReader(encrypted), testKey, testIV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "segment"; expected != string(b) {
+		t.Errorf("expected %s, but %s", expected, b)
+	}
+
+	if _, err := NewDecryptingReader(bytes.NewReader([]byte("short")), testKey, testIV); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+// newEncryptedTimeshiftServer returns a Client which serves the program
+// starting at 2016-11-12 22:00 on TBS with the segments "[0]" to "[3]",
+// where "[1]" and "[2]" are encrypted.
+// The returned func reports the number of the key requests.
+func newEncryptedTimeshiftServer(t *testing.T) (*Client, func() int, func()) {
+	var base string
+	keyRequests := 0
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml", "/v3/program/station/date/20161112/TBS.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=52973,CODECS=\"mp4a.40.5\"\n%s/chunklist.m3u8\n", base)
+		case "/chunklist.m3u8":
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:1\n")
+			fmt.Fprint(w, "#EXTINF:5,\n0.aac\n")
+			fmt.Fprintf(w, "#EXT-X-KEY:METHOD=AES-128,URI=\"key\",IV=0x%x\n", testIV)
+			fmt.Fprint(w, "#EXTINF:5,\n1.aac\n#EXTINF:5,\n2.aac\n")
+			fmt.Fprint(w, "#EXT-X-KEY:METHOD=NONE\n#EXTINF:5,\n3.aac\n#EXT-X-ENDLIST\n")
+		case "/key":
+			keyRequests++
+			w.Write(testKey)
+		case "/0.aac", "/3.aac":
+			fmt.Fprintf(w, "[%s]", r.URL.Path[1:2])
+		case "/1.aac", "/2.aac":
+			w.Write(encryptAES128(t, []byte("["+r.URL.Path[1:2]+"]"), testKey, testIV))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	base = c.URL.String()
+	return c, func() int { return keyRequests }, closer
+}
+
+func TestDownloadTimeshift_Encrypted(t *testing.T) {
+	c, keyRequests, closer := newEncryptedTimeshiftServer(t)
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = c.DownloadTimeshift(context.Background(), "TBS", start, &buf, WithConcurrency(1)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[0][1][2][3]"; expected != buf.String() {
+		t.Errorf("expected %s, but %s", expected, buf.String())
+	}
+	if expected, actual := 1, keyRequests(); expected != actual {
+		t.Errorf("expected %d, but %d", expected, actual)
+	}
+}
+
+func TestRecordTimeshift_Encrypted(t *testing.T) {
+	c, _, closer := newEncryptedTimeshiftServer(t)
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err = c.RecordTimeshift(context.Background(), "TBS", start, start.Add(20*time.Second), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[0][1][2][3]"; expected != buf.String() {
+		t.Errorf("expected %s, but %s", expected, buf.String())
+	}
+}
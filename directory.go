@@ -0,0 +1,53 @@
+package radiko
+
+import (
+	"context"
+	"fmt"
+)
+
+// numAreas is the number of radiko areas, JP1 to JP47.
+const numAreas = 47
+
+// allAreaIDs returns the IDs of all radiko areas.
+func allAreaIDs() []string {
+	ids := make([]string, numAreas)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("JP%d", i+1)
+	}
+	return ids
+}
+
+// WarmStationDirectory fetches the station lists of all areas
+// and caches them in the Client.
+// It returns the map from the areaID to the stations.
+// After it succeeds, GetRadioStations returns the cached stations
+// without sending a request until Close is called.
+func (c *Client) WarmStationDirectory(ctx context.Context) (map[string]RadioStations, error) {
+	areaIDs := allAreaIDs()
+	lists := make([]RadioStations, len(areaIDs))
+
+	err := c.parallel(ctx, len(areaIDs), func(ctx context.Context, i int) error {
+		stations, err := c.getRadioStations(ctx, areaIDs[i])
+		if err != nil {
+			return err
+		}
+		lists[i] = stations
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	directory := make(map[string]RadioStations, len(areaIDs))
+	cached := make(map[string]RadioStations, len(areaIDs))
+	for i, areaID := range areaIDs {
+		directory[areaID] = lists[i]
+		cached[areaID] = lists[i].clone()
+	}
+
+	c.mu.Lock()
+	c.stationDirectory = cached
+	c.mu.Unlock()
+
+	return directory, nil
+}
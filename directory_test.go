@@ -0,0 +1,63 @@
+package radiko
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWarmStationDirectory(t *testing.T) {
+	var requests int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch path.Base(r.URL.Path) {
+		case "JP13.xml", "JP14.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "station_list.xml"))
+		default:
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><stations></stations>`))
+		}
+	})
+	c, closer := newTestClient(t, handler)
+	defer closer()
+
+	directory, err := c.WarmStationDirectory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := numAreas; len(directory) != expected {
+		t.Errorf("expected %d areas, but %d", expected, len(directory))
+	}
+	if expected := 3; len(directory["JP14"]) != expected {
+		t.Errorf("expected %d stations, but %d", expected, len(directory["JP14"]))
+	}
+	if expected := 0; len(directory["JP27"]) != expected {
+		t.Errorf("expected %d stations, but %d", expected, len(directory["JP27"]))
+	}
+
+	sent := atomic.LoadInt32(&requests)
+	if sent != numAreas {
+		t.Errorf("expected %d requests, but %d", numAreas, sent)
+	}
+
+	stations, err := c.GetRadioStations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 3; len(stations) != expected {
+		t.Errorf("expected %d stations, but %d", expected, len(stations))
+	}
+	if actual := atomic.LoadInt32(&requests); actual != sent {
+		t.Errorf("expected the cache to be used, but %d requests were sent", actual-sent)
+	}
+
+	c.Close()
+	if _, err := c.GetRadioStations(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if actual := atomic.LoadInt32(&requests); actual != sent+1 {
+		t.Errorf("expected a request after Close, but %d", actual-sent)
+	}
+}
@@ -0,0 +1,403 @@
+package radiko
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/m3u8"
+)
+
+// DownloadOptions configures how segments are downloaded.
+type DownloadOptions struct {
+	// Retries is the number of retries for a failed segment.
+	Retries int
+	// MaxMissing is the number of segments that are allowed to fail
+	// after the retries. The failed segments are skipped.
+	MaxMissing int
+}
+
+// DownloadReport reports the result of the download.
+type DownloadReport struct {
+	// Segments is the number of segments written.
+	Segments int
+	// Missing is the list of segment urls which could not be downloaded.
+	Missing []string
+	// Discontinuities is the list of indexes of the segments in the playlist
+	// which start a new discontinuity. The timestamps of the output
+	// should be reset at these segments when it is muxed.
+	Discontinuities []int
+}
+
+// Segment is a media segment in the chunklist.
+// Key is set if the segment is encrypted.
+type Segment = m3u8.Segment
+
+// SegmentKey represents EXT-X-KEY of a Segment.
+type SegmentKey = m3u8.Key
+
+// DownloadSegments downloads the segments and writes them to w in order.
+// The segments are fetched concurrently, and the segments encrypted
+// with AES-128 are decrypted.
+// If more than opts.MaxMissing segments fail, it stops and returns
+// ErrTooManyMissingSegments with the report so far.
+// If opts is nil, a failed segment is not retried nor skipped.
+func (c *Client) DownloadSegments(ctx context.Context, segments []Segment, w io.Writer, opts *DownloadOptions) (*DownloadReport, error) {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	return c.downloadOrdered(ctx, segments, w, c.newTimeshiftDownload(nil), opts)
+}
+
+// downloadSegment returns the body of the segment.
+// The download is tried retries+1 times at most.
+func (c *Client) downloadSegment(ctx context.Context, u string, retries int) ([]byte, error) {
+	var err error
+	for i := 0; i <= retries; i++ {
+		var b []byte
+		if b, err = c.getSegment(ctx, u); err == nil {
+			return b, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, err
+}
+
+func (c *Client) getSegment(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", c.getUserAgent())
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readAPIError(resp)
+	}
+
+	var buf bytes.Buffer
+	if _, err = io.Copy(&buf, resp.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DownloadTimeshiftOption configures DownloadTimeshift.
+type DownloadTimeshiftOption func(*timeshiftDownload)
+
+type timeshiftDownload struct {
+	concurrency int
+	progress    func(done, total int)
+
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+// WithConcurrency sets the max number of the segments fetched concurrently.
+// If n is not positive, the default max concurrency is used.
+func WithConcurrency(n int) DownloadTimeshiftOption {
+	return func(d *timeshiftDownload) {
+		d.concurrency = n
+	}
+}
+
+// WithProgress sets the callback called after each segment is written.
+// total is the number of all the segments.
+// The callback is called from one goroutine at a time.
+func WithProgress(fn func(done, total int)) DownloadTimeshiftOption {
+	return func(d *timeshiftDownload) {
+		d.progress = fn
+	}
+}
+
+// DownloadTimeshift downloads the segments of the program which starts at start
+// and writes them to w in order.
+// The segments are fetched concurrently.
+// The segments encrypted with AES-128 are decrypted,
+// and the segments in the NG range of the program are skipped.
+// If it fails after some segments are written, it returns PartialWriteError.
+func (c *Client) DownloadTimeshift(ctx context.Context, stationID string, start time.Time, w io.Writer, opts ...DownloadTimeshiftOption) error {
+	d := c.newTimeshiftDownload(opts)
+
+	segments, err := c.timeshiftSegments(ctx, stationID, start)
+	if err != nil {
+		return err
+	}
+
+	report, err := c.downloadOrdered(ctx, segments, w, d, nil)
+	if err != nil && report.Segments > 0 {
+		return &PartialWriteError{Written: report.Segments, Total: len(segments), Err: err}
+	}
+	return err
+}
+
+// OpenTimeshift returns the reader of the program which starts at start,
+// which reads what DownloadTimeshift writes.
+// The segments are fetched and decrypted as the reader is consumed,
+// looking ahead as many segments as WithConcurrency sets.
+// Close stops fetching the segments.
+// If it fails after some segments are read, Read returns PartialWriteError.
+func (c *Client) OpenTimeshift(ctx context.Context, stationID string, start time.Time, opts ...DownloadTimeshiftOption) (io.ReadCloser, error) {
+	d := c.newTimeshiftDownload(opts)
+
+	segments, err := c.timeshiftSegments(ctx, stationID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		report, err := c.downloadOrdered(ctx, segments, pw, d, nil)
+		if err != nil && report.Segments > 0 {
+			err = &PartialWriteError{Written: report.Segments, Total: len(segments), Err: err}
+		}
+		pw.CloseWithError(err)
+	}()
+	return &timeshiftReader{PipeReader: pr, cancel: cancel, done: done}, nil
+}
+
+// timeshiftReader is the reader returned by OpenTimeshift.
+type timeshiftReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Close stops fetching the segments and waits for it.
+func (r *timeshiftReader) Close() error {
+	r.cancel()
+	err := r.PipeReader.Close()
+	<-r.done
+	return err
+}
+
+func (c *Client) newTimeshiftDownload(opts []DownloadTimeshiftOption) *timeshiftDownload {
+	d := &timeshiftDownload{}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.concurrency <= 0 {
+		d.concurrency = c.concurrency()
+	}
+	return d
+}
+
+// partSuffix and manifestSuffix are appended to the path of
+// DownloadTimeshiftToFile for the file being downloaded and its manifest.
+const (
+	partSuffix     = ".part"
+	manifestSuffix = ".part.json"
+)
+
+// downloadManifest records the progress of DownloadTimeshiftToFile.
+type downloadManifest struct {
+	// Total is the number of all the segments.
+	Total int `json:"total"`
+	// Segments is the number of the segments written.
+	Segments int `json:"segments"`
+	// Size is the size of the segments written.
+	Size int64 `json:"size"`
+}
+
+// DownloadTimeshiftToFile is like DownloadTimeshift, but writes the segments
+// to path+".part" and renames it to path on success.
+// The progress is recorded in path+".part.json" after each segment,
+// so if it fails or ctx is canceled, calling it again with the same path
+// resumes the download by skipping the segments already written.
+// If the playlist has changed since, the download starts over.
+func (c *Client) DownloadTimeshiftToFile(ctx context.Context, stationID string, start time.Time, path string, opts ...DownloadTimeshiftOption) error {
+	d := c.newTimeshiftDownload(opts)
+
+	segments, err := c.timeshiftSegments(ctx, stationID, start)
+	if err != nil {
+		return err
+	}
+
+	manifestPath := path + manifestSuffix
+	m := readDownloadManifest(manifestPath)
+	if m.Total != len(segments) || m.Segments > len(segments) {
+		m = downloadManifest{Total: len(segments)}
+	}
+
+	f, err := os.OpenFile(path+partSuffix, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() < m.Size {
+		m = downloadManifest{Total: len(segments)}
+	}
+	// Drop the bytes written after the last recorded segment.
+	if err = f.Truncate(m.Size); err != nil {
+		return err
+	}
+	if _, err = f.Seek(m.Size, io.SeekStart); err != nil {
+		return err
+	}
+
+	if progress, skipped := d.progress, m.Segments; progress != nil {
+		d.progress = func(done, total int) {
+			progress(skipped+done, skipped+total)
+		}
+	}
+
+	w := &manifestWriter{w: f, path: manifestPath, m: m}
+	if err = writeDownloadManifest(manifestPath, m); err != nil {
+		return err
+	}
+	report, err := c.downloadOrdered(ctx, segments[m.Segments:], w, d, nil)
+	if err != nil {
+		if written := report.Segments + m.Segments; written > 0 {
+			return &PartialWriteError{Written: written, Total: len(segments), Err: err}
+		}
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(path+partSuffix, path); err != nil {
+		return err
+	}
+	return os.Remove(manifestPath)
+}
+
+// manifestWriter records the progress in the manifest after each write,
+// which is called with a segment by downloadOrdered.
+type manifestWriter struct {
+	w    io.Writer
+	path string
+	m    downloadManifest
+}
+
+func (mw *manifestWriter) Write(b []byte) (int, error) {
+	n, err := mw.w.Write(b)
+	if err != nil {
+		return n, err
+	}
+	mw.m.Segments++
+	mw.m.Size += int64(n)
+	return n, writeDownloadManifest(mw.path, mw.m)
+}
+
+// readDownloadManifest returns the manifest in path.
+// If it does not exist or is broken, it returns the empty one.
+func readDownloadManifest(path string) downloadManifest {
+	var m downloadManifest
+	b, err := ioutil.ReadFile(path)
+	if err != nil || json.Unmarshal(b, &m) != nil {
+		return downloadManifest{}
+	}
+	return m
+}
+
+func writeDownloadManifest(path string, m downloadManifest) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// downloadOrdered fetches the segments concurrently, decrypts them
+// and writes them in order.
+// At most d.concurrency segments are fetched or waiting to be written.
+// The failed segments are retried and skipped as opts sets.
+// If opts is nil, the error of the first failed segment is returned.
+func (c *Client) downloadOrdered(ctx context.Context, segments []m3u8.Segment, w io.Writer, d *timeshiftDownload, opts *DownloadOptions) (*DownloadReport, error) {
+	var retries int
+	if opts != nil {
+		retries = opts.Retries
+	}
+
+	report := &DownloadReport{}
+	for i, s := range segments {
+		if s.Discontinuity {
+			report.Discontinuities = append(report.Discontinuities, i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		b   []byte
+		err error
+	}
+	results := make([]chan result, len(segments))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	sem := make(chan struct{}, d.concurrency)
+	go func() {
+		for i, s := range segments {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(i int, s m3u8.Segment) {
+				b, err := c.downloadSegment(ctx, s.URI, retries)
+				if err == nil {
+					b, err = c.decryptSegment(ctx, b, s, d)
+				}
+				results[i] <- result{b, err}
+			}(i, s)
+		}
+	}()
+
+	for i, s := range segments {
+		var r result
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			return report, ctx.Err()
+		}
+		<-sem
+		if r.err != nil {
+			if opts == nil {
+				return report, r.err
+			}
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			c.logf("radiko: failed to download segment %s: %s", s.URI, r.err)
+			report.Missing = append(report.Missing, s.URI)
+			if len(report.Missing) > opts.MaxMissing {
+				return report, ErrTooManyMissingSegments
+			}
+		} else {
+			if _, err := w.Write(r.b); err != nil {
+				return report, err
+			}
+			report.Segments++
+		}
+		if d.progress != nil {
+			d.progress(i+1, len(segments))
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,360 @@
+package radiko
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+func newSegmentServer(t *testing.T) (*Client, string, func()) {
+	var (
+		mu    sync.Mutex
+		flaky int
+	)
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1.aac", "/3.aac":
+			w.Write([]byte(r.URL.Path))
+		case "/flaky.aac":
+			mu.Lock()
+			flaky++
+			n := flaky
+			mu.Unlock()
+			if n == 1 {
+				http.Error(w, "temporary", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(r.URL.Path))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return c, c.URL.String(), closer
+}
+
+func TestDownloadSegments_MaxMissing(t *testing.T) {
+	c, base, closer := newSegmentServer(t)
+	defer closer()
+
+	segments := []Segment{{URI: base + "/1.aac"}, {URI: base + "/2.aac"}, {URI: base + "/3.aac"}}
+	var buf bytes.Buffer
+	report, err := c.DownloadSegments(context.Background(), segments, &buf, &DownloadOptions{
+		Retries:    2,
+		MaxMissing: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/1.aac/3.aac"; expected != buf.String() {
+		t.Errorf("expected %s, but %s", expected, buf.String())
+	}
+	if report.Segments != 2 {
+		t.Errorf("expected 2 segments, but %d", report.Segments)
+	}
+	if len(report.Missing) != 1 || report.Missing[0] != segments[1].URI {
+		t.Errorf("unexpected missing segments: %v", report.Missing)
+	}
+}
+
+func TestDownloadSegments_TooManyMissing(t *testing.T) {
+	c, base, closer := newSegmentServer(t)
+	defer closer()
+
+	segments := []Segment{{URI: base + "/1.aac"}, {URI: base + "/2.aac"}, {URI: base + "/3.aac"}}
+	var buf bytes.Buffer
+	report, err := c.DownloadSegments(context.Background(), segments, &buf, nil)
+	if err != ErrTooManyMissingSegments {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(report.Missing) != 1 {
+		t.Errorf("unexpected missing segments: %v", report.Missing)
+	}
+	if expected := "/1.aac"; expected != buf.String() {
+		t.Errorf("expected %s, but %s", expected, buf.String())
+	}
+}
+
+func TestDownloadSegments_Retry(t *testing.T) {
+	c, base, closer := newSegmentServer(t)
+	defer closer()
+
+	segments := []Segment{{URI: base + "/flaky.aac"}}
+	var buf bytes.Buffer
+	report, err := c.DownloadSegments(context.Background(), segments, &buf, &DownloadOptions{
+		Retries: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Missing) != 0 {
+		t.Errorf("unexpected missing segments: %v", report.Missing)
+	}
+	if expected := "/flaky.aac"; expected != buf.String() {
+		t.Errorf("expected %s, but %s", expected, buf.String())
+	}
+}
+
+// newTimeshiftServer returns a Client which serves the program starting at
+// 2016-11-12 22:00 on TBS with the n segments "/0.aac" to "/{n-1}.aac".
+// The segments are served in reverse order of the index by delaying them.
+// If fail is not negative, the segment of the index fails.
+func newTimeshiftServer(t *testing.T, n, fail int) (*Client, func()) {
+	var base string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+			return
+		case "/v2/api/ts/playlist.m3u8":
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=52973,CODECS=\"mp4a.40.5\"\n%s/chunklist.m3u8\n", base)
+			return
+		case "/chunklist.m3u8":
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:1\n")
+			for i := 0; i < n; i++ {
+				fmt.Fprintf(w, "#EXTINF:5,\n%d.aac\n", i)
+			}
+			fmt.Fprint(w, "#EXT-X-ENDLIST\n")
+			return
+		}
+
+		var i int
+		if _, err := fmt.Sscanf(r.URL.Path, "/%d.aac", &i); err != nil || i >= n {
+			http.NotFound(w, r)
+			return
+		}
+		time.Sleep(time.Duration(n-i) * time.Millisecond)
+		if i == fail {
+			http.Error(w, "failed", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, "[%d]", i)
+	}))
+	base = c.URL.String()
+	return c, closer
+}
+
+func TestDownloadTimeshift(t *testing.T) {
+	c, closer := newTimeshiftServer(t, 10, -1)
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = c.DownloadTimeshift(context.Background(), "TBS", start, &buf, WithConcurrency(3)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[0][1][2][3][4][5][6][7][8][9]"; expected != buf.String() {
+		t.Errorf("expected %s, but %s", expected, buf.String())
+	}
+}
+
+func TestDownloadTimeshift_Progress(t *testing.T) {
+	c, closer := newTimeshiftServer(t, 5, -1)
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []string
+	err = c.DownloadTimeshift(context.Background(), "TBS", start, ioutil.Discard, WithProgress(func(done, total int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", done, total))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "1/5 2/5 3/5 4/5 5/5", strings.Join(progress, " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+}
+
+func TestDownloadTimeshift_PartialWrite(t *testing.T) {
+	c, closer := newTimeshiftServer(t, 10, 5)
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = c.DownloadTimeshift(context.Background(), "TBS", start, &buf, WithConcurrency(3))
+	partialErr, ok := err.(*PartialWriteError)
+	if !ok {
+		t.Fatalf("expected PartialWriteError, but %v", err)
+	}
+	if partialErr.Written != 5 || partialErr.Total != 10 {
+		t.Errorf("expected 5 of 10, but %d of %d", partialErr.Written, partialErr.Total)
+	}
+	if expected := "[0][1][2][3][4]"; expected != buf.String() {
+		t.Errorf("expected %s, but %s", expected, buf.String())
+	}
+}
+
+func TestDownloadTimeshift_Cancel(t *testing.T) {
+	c, closer := newTimeshiftServer(t, 10, -1)
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := writerFunc(func(p []byte) (int, error) {
+		cancel()
+		return len(p), nil
+	})
+	err = c.DownloadTimeshift(ctx, "TBS", start, w, WithConcurrency(1))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %s, but %v", context.Canceled, err)
+	}
+}
+
+func TestDownloadTimeshiftToFile_Resume(t *testing.T) {
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "radiko")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "TBS.aac")
+
+	c, closer := newTimeshiftServer(t, 10, 5)
+	defer closer()
+	err = c.DownloadTimeshiftToFile(context.Background(), "TBS", start, path, WithConcurrency(3))
+	if partialErr, ok := err.(*PartialWriteError); !ok || partialErr.Written != 5 {
+		t.Fatalf("expected PartialWriteError of 5 segments, but %v", err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected no file, but %v", err)
+	}
+
+	// The bytes after the last recorded segment are dropped.
+	f, err := os.OpenFile(path+partSuffix, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("[5"))
+	f.Close()
+
+	c, closer = newTimeshiftServer(t, 10, -1)
+	defer closer()
+	var progress []string
+	err = c.DownloadTimeshiftToFile(context.Background(), "TBS", start, path, WithProgress(func(done, total int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", done, total))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "6/10 7/10 8/10 9/10 10/10", strings.Join(progress, " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[0][1][2][3][4][5][6][7][8][9]"; expected != string(b) {
+		t.Errorf("expected %s, but %s", expected, b)
+	}
+	for _, p := range []string{path + partSuffix, path + manifestSuffix} {
+		if _, err = os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no file, but %v", p, err)
+		}
+	}
+}
+
+func TestOpenTimeshift(t *testing.T) {
+	c, closer := newTimeshiftServer(t, 10, -1)
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.OpenTimeshift(context.Background(), "TBS", start, WithConcurrency(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[0][1][2][3][4][5][6][7][8][9]"; expected != string(b) {
+		t.Errorf("expected %s, but %s", expected, b)
+	}
+	if err = r.Close(); err != nil {
+		t.Error(err)
+	}
+
+	// Close before reading all.
+	r, err = c.OpenTimeshift(context.Background(), "TBS", start, WithConcurrency(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = make([]byte, 3)
+	if _, err = io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[0]"; expected != string(b) {
+		t.Errorf("expected %s, but %s", expected, b)
+	}
+	if err = r.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err = r.Read(b); err != io.ErrClosedPipe {
+		t.Errorf("expected %s, but %v", io.ErrClosedPipe, err)
+	}
+}
+
+func TestOpenTimeshift_PartialWrite(t *testing.T) {
+	c, closer := newTimeshiftServer(t, 10, 5)
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.OpenTimeshift(context.Background(), "TBS", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if partialErr, ok := err.(*PartialWriteError); !ok || partialErr.Written != 5 {
+		t.Errorf("expected PartialWriteError of 5 segments, but %v", err)
+	}
+	if expected := "[0][1][2][3][4]"; expected != string(b) {
+		t.Errorf("expected %s, but %s", expected, b)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,63 @@
+package radiko
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+// ProgramDTO is a program cleaned for JSON serialization.
+// The times are formatted in RFC3339, and the texts are plain.
+type ProgramDTO struct {
+	ID              string            `json:"id"`
+	MasterID        string            `json:"master_id,omitempty"`
+	Title           string            `json:"title"`
+	SubTitle        string            `json:"sub_title,omitempty"`
+	Start           string            `json:"start"`
+	End             string            `json:"end"`
+	DurationMinutes int               `json:"duration_minutes"`
+	Performers      []string          `json:"performers"`
+	Description     string            `json:"description,omitempty"`
+	Info            string            `json:"info,omitempty"`
+	URL             string            `json:"url,omitempty"`
+	Images          map[string]string `json:"images,omitempty"`
+	CanTimeshift    bool              `json:"can_timeshift"`
+}
+
+// ToDTO returns the program as ProgramDTO.
+// The times which cannot be parsed are left empty.
+func (p Prog) ToDTO() ProgramDTO {
+	dto := ProgramDTO{
+		ID:           p.ID,
+		MasterID:     p.MasterID,
+		Title:        p.Title,
+		SubTitle:     p.SubTitle,
+		Performers:   p.Performers(),
+		Description:  stripHTML(p.Desc),
+		Info:         stripHTML(p.Info),
+		URL:          p.URL,
+		CanTimeshift: p.CanTimeshift(),
+	}
+	if len(p.Images) > 0 {
+		dto.Images = make(map[string]string, len(p.Images))
+		for k, v := range p.Images {
+			dto.Images[k] = v
+		}
+	}
+
+	ft, ftErr := util.ParseRadikoTime(p.Ft)
+	if ftErr == nil {
+		dto.Start = ft.Format(time.RFC3339)
+	}
+	to, toErr := util.ParseRadikoTime(p.To)
+	if toErr == nil {
+		dto.End = to.Format(time.RFC3339)
+	}
+	if ftErr == nil && toErr == nil {
+		dto.DurationMinutes = int(to.Sub(ft) / time.Minute)
+	} else if sec, err := strconv.Atoi(p.Dur); err == nil {
+		dto.DurationMinutes = sec / 60
+	}
+	return dto
+}
@@ -0,0 +1,62 @@
+package radiko
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProg_ToDTO(t *testing.T) {
+	p := Prog{
+		ID:    "10001",
+		Ft:    "20161112230000",
+		To:    "20161113003000",
+		Dur:   "5400",
+		Title: "ライムスター宇多丸のウィークエンド・シャッフル",
+		Pfm:   "宇多丸（ライムスター）、熊崎風斗 / 日比麻音子",
+		Desc:  "<p>番組の説明<br />2行目</p>",
+	}
+	dto := p.ToDTO()
+
+	if expected := "2016-11-12T23:00:00+09:00"; expected != dto.Start {
+		t.Errorf("expected %s, but %s", expected, dto.Start)
+	}
+	if expected := "2016-11-13T00:30:00+09:00"; expected != dto.End {
+		t.Errorf("expected %s, but %s", expected, dto.End)
+	}
+	if expected := 90; expected != dto.DurationMinutes {
+		t.Errorf("expected %d, but %d", expected, dto.DurationMinutes)
+	}
+	expectedPfm := []string{"宇多丸（ライムスター）", "熊崎風斗", "日比麻音子"}
+	if len(dto.Performers) != len(expectedPfm) {
+		t.Fatalf("expected %v, but %v", expectedPfm, dto.Performers)
+	}
+	for i := range expectedPfm {
+		if expectedPfm[i] != dto.Performers[i] {
+			t.Errorf("expected %s, but %s", expectedPfm[i], dto.Performers[i])
+		}
+	}
+	if expected := "番組の説明\n2行目"; expected != dto.Description {
+		t.Errorf("expected %q, but %q", expected, dto.Description)
+	}
+	if !dto.CanTimeshift {
+		t.Error("expected can_timeshift")
+	}
+
+	if _, err := json.Marshal(dto); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProg_ToDTO_EmptyPerformers(t *testing.T) {
+	b, err := json.Marshal(Prog{}.ToDTO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["performers"].([]interface{}); !ok {
+		t.Errorf("expected performers to be an array, but %v", m["performers"])
+	}
+}
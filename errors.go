@@ -1,8 +1,111 @@
 package radiko
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
 
 var (
 	// ErrProgramNotFound is returned when a program not found
 	ErrProgramNotFound = errors.New("program not found")
+	// ErrStationNotFound is returned when a station not found
+	ErrStationNotFound = errors.New("station not found")
+	// ErrInvalidStationID is returned when a station id is empty or malformed
+	ErrInvalidStationID = errors.New("invalid station id")
+	// ErrInvalidAreaID is returned when an area id is not one of JP1 to JP47
+	ErrInvalidAreaID = errors.New("invalid area id")
+	// ErrTooManyMissingSegments is returned when segments failed to download
+	// more than the tolerance
+	ErrTooManyMissingSegments = errors.New("too many missing segments")
+	// ErrTimeshiftNotAllowed is returned when a program is not allowed in timeshift
+	ErrTimeshiftNotAllowed = errors.New("timeshift not allowed")
+	// ErrTimeshiftExpired is returned when a program is older than
+	// the timeshift window
+	ErrTimeshiftExpired = errors.New("timeshift expired")
+	// ErrAuthFailed is returned when the auth flow fails
+	// or the auth token is rejected
+	ErrAuthFailed = errors.New("auth failed")
+	// ErrAreaRestricted is returned when a station is not available
+	// in the Client's area
+	ErrAreaRestricted = errors.New("area restricted")
+	// ErrPremiumRequired is returned when the logged in account
+	// is not a premium member
+	ErrPremiumRequired = errors.New("premium member required")
 )
+
+// maxAPIErrorBody is the max length of the body kept in APIError.
+const maxAPIErrorBody = 256
+
+// APIError is returned when radiko responds with an unexpected status.
+// If the status is 401 or 403, it wraps ErrAuthFailed.
+type APIError struct {
+	StatusCode int
+	// Body is the beginning of the response body.
+	Body string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("invalid status code: %d: %s", e.StatusCode, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	if e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden {
+		return ErrAuthFailed
+	}
+	return nil
+}
+
+// newAPIError returns APIError with the snippet of body.
+func newAPIError(statusCode int, body []byte) *APIError {
+	if len(body) > maxAPIErrorBody {
+		body = body[:maxAPIErrorBody]
+	}
+	return &APIError{StatusCode: statusCode, Body: string(body)}
+}
+
+// readAPIError returns APIError with the beginning of the response body.
+func readAPIError(resp *http.Response) *APIError {
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxAPIErrorBody))
+	return newAPIError(resp.StatusCode, b)
+}
+
+// PartialWriteError is returned when a download stops
+// after some segments are written.
+type PartialWriteError struct {
+	// Written is the number of the segments written.
+	Written int
+	// Total is the number of all the segments.
+	Total int
+	Err   error
+}
+
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("%d of %d segments written: %s", e.Written, e.Total, e.Err)
+}
+
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
+
+// StationErrors is returned by the methods which fetch multiple stations
+// when some of the stations fail. It maps the station id to its error.
+type StationErrors map[string]error
+
+func (e StationErrors) Error() string {
+	ids := make([]string, 0, len(e))
+	for id := range e {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	msgs := make([]string, len(ids))
+	for i, id := range ids {
+		msgs[i] = fmt.Sprintf("%s: %s", id, e[id])
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,38 @@
+package radiko
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	err := fmt.Errorf("playlist: %w", newAPIError(http.StatusForbidden, []byte(strings.Repeat("x", 300))))
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, but %v", err)
+	}
+	if expected := maxAPIErrorBody; len(apiErr.Body) != expected {
+		t.Errorf("expected %d, but %d", expected, len(apiErr.Body))
+	}
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected %s, but %v", ErrAuthFailed, err)
+	}
+
+	if err := newAPIError(http.StatusInternalServerError, nil); errors.Is(err, ErrAuthFailed) {
+		t.Errorf("%v should not be %s", err, ErrAuthFailed)
+	}
+}
+
+func TestStationErrors(t *testing.T) {
+	err := StationErrors{
+		"TBS": ErrProgramNotFound,
+		"LFR": ErrStationNotFound,
+	}
+	if expected := "LFR: station not found; TBS: program not found"; expected != err.Error() {
+		t.Errorf("expected %s, but %s", expected, err.Error())
+	}
+}
@@ -0,0 +1,36 @@
+package radiko
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"path"
+)
+
+// Genre is a category of the programs.
+// The top level genres have no ParentID.
+type Genre struct {
+	ID       string `xml:"id,attr" json:"id"`
+	Name     string `xml:",chardata" json:"name"`
+	ParentID string `xml:"parent_id,attr" json:"parent_id,omitempty"`
+}
+
+// GetGenres returns the genres of the programs.
+func (c *Client) GetGenres(ctx context.Context) ([]Genre, error) {
+	apiEndpoint := path.Join(apiV3, "program/genre.xml")
+
+	var d genresData
+	err := c.getXML(ctx, apiEndpoint, &Params{}, func(r io.Reader) error {
+		d = genresData{}
+		return xml.NewDecoder(r).Decode(&d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.Genres, nil
+}
+
+type genresData struct {
+	XMLName xml.Name `xml:"genres"`
+	Genres  []Genre  `xml:"genre"`
+}
@@ -0,0 +1,62 @@
+package radiko
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+func TestGetGenres(t *testing.T) {
+	var requested string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		http.ServeFile(w, r, filepath.Join(testdataDir, "genres.xml"))
+	}))
+	defer closer()
+
+	genres, err := c.GetGenres(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/v3/program/genre.xml"; expected != requested {
+		t.Errorf("expected %s, but %s", expected, requested)
+	}
+
+	if expected := 4; len(genres) != expected {
+		t.Fatalf("expected %d genres, but %d", expected, len(genres))
+	}
+	expected := Genre{ID: "P002", Name: "音楽", ParentID: "P"}
+	if genres[2] != expected {
+		t.Errorf("expected %v, but %v", expected, genres[2])
+	}
+	if genres[0].ParentID != "" {
+		t.Errorf("expected no parent, but %s", genres[0].ParentID)
+	}
+}
+
+func TestProg_Genre(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	date, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	progs, err := c.GetProgramsByStation(context.Background(), "TBS", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progs) < 2 {
+		t.Fatalf("expected 2 programs at least, but %d", len(progs))
+	}
+
+	if expected := (Genre{}); progs[0].Genre != expected {
+		t.Errorf("expected no genre, but %v", progs[0].Genre)
+	}
+	if expected := (Genre{ID: "P002", Name: "音楽"}); progs[1].Genre != expected {
+		t.Errorf("expected %v, but %v", expected, progs[1].Genre)
+	}
+}
@@ -0,0 +1,99 @@
+package radiko
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const areaIDTokyo = "JP13"
+
+var (
+	outsideJP  bool
+	onlineTest bool
+
+	testdataDir string
+)
+
+func init() {
+	// Skip tests if outside Japan.
+	OUTSIDEJP := os.Getenv("GO_RADIKO_OUTSIDE_JP")
+	if len(OUTSIDEJP) > 0 {
+		outsideJP = true
+	}
+
+	// Run online tests against the real radiko.jp.
+	ONLINETEST := os.Getenv("GO_RADIKO_ONLINE_TEST")
+	if len(ONLINETEST) > 0 {
+		onlineTest = true
+	}
+
+	GOPATH := os.Getenv("GOPATH")
+	testdataDir = filepath.Join(GOPATH, "src", "github.com/chikulla/go-radiko", "testdata")
+}
+
+// For skipping tests.
+// radiko.jp restricts use from outside Japan.
+func isOutsideJP() bool {
+	return outsideJP
+}
+
+// newOnlineTestClient returns a Client for online tests.
+// It skips the test unless GO_RADIKO_ONLINE_TEST is set.
+// The area check is bypassed by using the fixed areaID
+// instead of the one detected from the current IP.
+func newOnlineTestClient(t *testing.T) *Client {
+	if !onlineTest {
+		t.Skip("Skipping online test. Set GO_RADIKO_ONLINE_TEST to run.")
+	}
+
+	c, err := New("")
+	if err != nil {
+		t.Fatalf("Failed to construct client: %s", err)
+	}
+	c.SetAreaID(areaIDTokyo)
+	return c
+}
+
+// Should restore defaultHTTPClient if SetHTTPClient is called.
+func teardownHTTPClient() {
+	SetHTTPClient(&http.Client{Timeout: defaultHTTPTimeout})
+}
+
+func createTestTempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "test-go-radiko")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// newTestClient returns a Client that sends requests to a test server.
+// The returned func closes the server.
+func newTestClient(t *testing.T, handler http.Handler) (*Client, func()) {
+	server := httptest.NewServer(handler)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		server.Close()
+		t.Fatalf("Failed to parse test server url: %s", err)
+	}
+
+	c := &Client{
+		URL:        u,
+		httpClient: server.Client(),
+		areaID:     areaIDTokyo,
+	}
+	return c, server.Close
+}
+
+// serveTestdata returns a handler that serves the named file in testdataDir.
+func serveTestdata(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join(testdataDir, name))
+	})
+}
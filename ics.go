@@ -0,0 +1,133 @@
+package radiko
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+const (
+	icsDatetimeLayout = "20060102T150405"
+	// icsLineLength is the max octets of a content line without CRLF.
+	icsLineLength = 75
+)
+
+// icsTimezone is the VTIMEZONE of Asia/Tokyo, which has no DST.
+var icsTimezone = []string{
+	"BEGIN:VTIMEZONE",
+	"TZID:Asia/Tokyo",
+	"BEGIN:STANDARD",
+	"DTSTART:19700101T000000",
+	"TZOFFSETFROM:+0900",
+	"TZOFFSETTO:+0900",
+	"TZNAME:JST",
+	"END:STANDARD",
+	"END:VTIMEZONE",
+}
+
+// ProgramsToICS returns the programs as an iCalendar with one VEVENT
+// per program. The times are in Asia/Tokyo, and the UID of each event
+// is derived from stationName and the start time, so it is stable.
+func ProgramsToICS(stationName string, progs []Prog) ([]byte, error) {
+	var buf bytes.Buffer
+	w := func(line string) {
+		buf.WriteString(foldICSLine(line))
+		buf.WriteString("\r\n")
+	}
+
+	w("BEGIN:VCALENDAR")
+	w("VERSION:2.0")
+	w("PRODID:-//go-radiko//go-radiko//EN")
+	w("CALSCALE:GREGORIAN")
+	w("X-WR-CALNAME:" + escapeICSText(stationName))
+	w("X-WR-TIMEZONE:Asia/Tokyo")
+	for _, line := range icsTimezone {
+		w(line)
+	}
+
+	stamp := time.Now().UTC().Format(icsDatetimeLayout) + "Z"
+	for _, p := range progs {
+		ft, err := p.StartTime()
+		if err != nil {
+			return nil, err
+		}
+		to, err := p.EndTime()
+		if err != nil {
+			return nil, err
+		}
+
+		w("BEGIN:VEVENT")
+		w("UID:" + icsUID(stationName, ft))
+		w("DTSTAMP:" + stamp)
+		w("DTSTART;TZID=Asia/Tokyo:" + ft.In(util.Location()).Format(icsDatetimeLayout))
+		w("DTEND;TZID=Asia/Tokyo:" + to.In(util.Location()).Format(icsDatetimeLayout))
+		w("SUMMARY:" + escapeICSText(p.Title))
+		if desc := icsDescription(p); desc != "" {
+			w("DESCRIPTION:" + escapeICSText(desc))
+		}
+		if p.URL != "" {
+			w("URL:" + p.URL)
+		}
+		w("LOCATION:" + escapeICSText(stationName))
+		w("END:VEVENT")
+	}
+	w("END:VCALENDAR")
+	return buf.Bytes(), nil
+}
+
+func icsUID(stationName string, ft time.Time) string {
+	sum := sha256.Sum256([]byte(stationName + "/" + util.Datetime(ft)))
+	return hex.EncodeToString(sum[:16]) + "@go-radiko"
+}
+
+// icsDescription returns the plain description and the performers.
+func icsDescription(p Prog) string {
+	var lines []string
+	if desc := stripHTML(p.Desc); desc != "" {
+		lines = append(lines, desc)
+	}
+	if p.Pfm != "" {
+		lines = append(lines, p.Pfm)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var icsTextEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+func escapeICSText(s string) string {
+	return icsTextEscaper.Replace(s)
+}
+
+// foldICSLine folds the line into the lines of icsLineLength octets,
+// not splitting UTF-8 characters.
+func foldICSLine(line string) string {
+	if len(line) <= icsLineLength {
+		return line
+	}
+
+	var b strings.Builder
+	n := 0
+	limit := icsLineLength
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 0
+			// The leading space is counted.
+			limit = icsLineLength - 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
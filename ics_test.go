@@ -0,0 +1,77 @@
+package radiko
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgramsToICS(t *testing.T) {
+	progs := []Prog{
+		{
+			Ft:    "20161112220000",
+			To:    "20161113000000",
+			Title: "ライムスター宇多丸のウィークエンド・シャッフル",
+			Desc:  "<p>映画評, 音楽; トーク</p>",
+			Pfm:   "宇多丸",
+		},
+		{Ft: "20161112240000", To: "20161112250000", Title: "深夜"},
+	}
+
+	b, err := ProgramsToICS("TBSラジオ", progs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ics := string(b)
+
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART;TZID=Asia/Tokyo:20161112T220000\r\n",
+		"DTEND;TZID=Asia/Tokyo:20161113T000000\r\n",
+		"DTSTART;TZID=Asia/Tokyo:20161113T000000\r\n",
+		"DESCRIPTION:映画評\\, 音楽\\; トーク\\n宇多丸\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, expected) {
+			t.Errorf("expected %q in %s", expected, ics)
+		}
+	}
+	if expected := 2; strings.Count(ics, "BEGIN:VEVENT") != expected {
+		t.Errorf("expected %d events, but %d", expected, strings.Count(ics, "BEGIN:VEVENT"))
+	}
+	for _, line := range strings.Split(ics, "\r\n") {
+		if len(line) > icsLineLength {
+			t.Errorf("line is not folded: %s", line)
+		}
+	}
+
+	// The UIDs are stable.
+	again, err := ProgramsToICS("TBSラジオ", progs[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := "UID:" + icsUID("TBSラジオ", mustStartTime(t, progs[0]))
+	if !strings.Contains(ics, uid) || !strings.Contains(string(again), uid) {
+		t.Errorf("expected %s in both", uid)
+	}
+
+	if _, err := ProgramsToICS("TBS", []Prog{{Ft: "invalid"}}); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("あ", 40)
+	folded := foldICSLine(line)
+	if unfolded := strings.Replace(folded, "\r\n ", "", -1); unfolded != line {
+		t.Errorf("expected %s, but %s", line, unfolded)
+	}
+}
+
+func mustStartTime(t *testing.T, p Prog) time.Time {
+	ft, err := p.StartTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ft
+}
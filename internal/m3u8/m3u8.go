@@ -1,8 +1,12 @@
 package m3u8
 
 import (
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/grafov/m3u8"
 )
@@ -21,6 +25,48 @@ func GetURI(input io.Reader) (string, error) {
 	return p.Variants[0].URI, nil
 }
 
+// VariantPref is the preference of the variant in a master playlist.
+type VariantPref int
+
+const (
+	// VariantFirst selects the first variant.
+	VariantFirst VariantPref = iota
+	// VariantHighest selects the variant of the highest bandwidth.
+	VariantHighest
+	// VariantLowest selects the variant of the lowest bandwidth.
+	VariantLowest
+)
+
+// SelectVariant returns the uri of the variant in the master playlist
+// selected by pref. If variants have the same bandwidth, the first one is selected.
+func SelectVariant(input io.Reader, pref VariantPref) (string, error) {
+	playlist, listType, err := m3u8.DecodeFrom(input, true)
+	if err != nil {
+		return "", err
+	}
+	if listType != m3u8.MASTER {
+		return "", errors.New("invalid m3u8 format")
+	}
+	p := playlist.(*m3u8.MasterPlaylist)
+
+	var selected *m3u8.Variant
+	for _, v := range p.Variants {
+		if v == nil {
+			continue
+		}
+		switch {
+		case selected == nil,
+			pref == VariantHighest && v.Bandwidth > selected.Bandwidth,
+			pref == VariantLowest && v.Bandwidth < selected.Bandwidth:
+			selected = v
+		}
+	}
+	if selected == nil {
+		return "", errors.New("invalid m3u8 format")
+	}
+	return selected.URI, nil
+}
+
 // GetChunklist returns a slice of uri string.
 func GetChunklist(input io.Reader) ([]string, error) {
 	playlist, listType, err := m3u8.DecodeFrom(input, true)
@@ -37,3 +83,117 @@ func GetChunklist(input io.Reader) ([]string, error) {
 	}
 	return chunklist, nil
 }
+
+// Segment represents a media segment.
+type Segment struct {
+	URI string
+	// Duration is the duration of the segment.
+	Duration time.Duration
+	// ProgramDateTime is the time of the first sample of the segment.
+	// It is zero if the playlist does not have EXT-X-PROGRAM-DATE-TIME.
+	ProgramDateTime time.Time
+	// Discontinuity is true if the segment follows EXT-X-DISCONTINUITY,
+	// that is, the encoding or timestamps may change from the previous one.
+	Discontinuity bool
+	// Key is the key the segment is encrypted with.
+	// It is nil if the segment is not encrypted.
+	Key *Key
+}
+
+// Key represents EXT-X-KEY.
+type Key struct {
+	// Method is the encryption method, e.g. AES-128.
+	Method string
+	URI    string
+	// IV is the initialization vector.
+	// If EXT-X-KEY has no IV, the media sequence number of the segment is used.
+	IV []byte
+}
+
+// GetSegments returns a slice of the media segment.
+func GetSegments(input io.Reader) ([]Segment, error) {
+	playlist, listType, err := m3u8.DecodeFrom(input, true)
+	if err != nil {
+		return nil, err
+	}
+	if listType != m3u8.MEDIA {
+		return nil, errors.New("invalid m3u8 format")
+	}
+	p := playlist.(*m3u8.MediaPlaylist)
+
+	var (
+		segments []Segment
+		key      *m3u8.Key
+	)
+	for i, v := range p.Segments {
+		if v == nil {
+			continue
+		}
+		// EXT-X-KEY applies to all the following segments.
+		if v.Key != nil {
+			key = v.Key
+			if key.Method == "NONE" {
+				key = nil
+			}
+		}
+
+		s := Segment{
+			URI:             v.URI,
+			Duration:        time.Duration(v.Duration * float64(time.Second)),
+			ProgramDateTime: v.ProgramDateTime,
+			Discontinuity:   v.Discontinuity,
+		}
+		if key != nil {
+			iv, err := parseIV(key.IV, p.SeqNo+uint64(i))
+			if err != nil {
+				return nil, err
+			}
+			s.Key = &Key{Method: key.Method, URI: key.URI, IV: iv}
+		}
+		segments = append(segments, s)
+	}
+	return segments, nil
+}
+
+// parseIV returns the IV in hexadecimal, such as 0x0123...,
+// or the sequence number as a 16 bytes big-endian if iv is empty.
+func parseIV(iv string, seq uint64) ([]byte, error) {
+	if iv == "" {
+		b := make([]byte, 16)
+		binary.BigEndian.PutUint64(b[8:], seq)
+		return b, nil
+	}
+
+	s := strings.TrimPrefix(strings.TrimPrefix(iv, "0x"), "0X")
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 16 {
+		return nil, errors.New("invalid IV: " + iv)
+	}
+	return b, nil
+}
+
+// TotalDuration returns the sum of the durations in EXTINF of the segments.
+func TotalDuration(segments []Segment) time.Duration {
+	var d time.Duration
+	for _, s := range segments {
+		d += s.Duration
+	}
+	return d
+}
+
+// SplitAtDiscontinuity splits the segments before each segment
+// which has Discontinuity.
+func SplitAtDiscontinuity(segments []Segment) [][]Segment {
+	var parts [][]Segment
+	start := 0
+	for i, s := range segments {
+		if s.Discontinuity && i > start {
+			parts = append(parts, segments[start:i])
+			start = i
+		}
+	}
+	if start < len(segments) {
+		parts = append(parts, segments[start:])
+	}
+	return parts
+}
@@ -2,9 +2,12 @@ package m3u8
 
 import (
 	"bufio"
+	"encoding/hex"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func readTestData(fileName string) *os.File {
@@ -31,6 +34,32 @@ func TestGetURI(t *testing.T) {
 	}
 }
 
+func TestSelectVariant(t *testing.T) {
+	cases := []struct {
+		pref     VariantPref
+		expected string
+	}{
+		{VariantFirst, "https://radiko.jp/v2/api/ts/chunklist/medium.m3u8"},
+		{VariantHighest, "https://radiko.jp/v2/api/ts/chunklist/high.m3u8"},
+		{VariantLowest, "https://radiko.jp/v2/api/ts/chunklist/low.m3u8"},
+	}
+	for _, c := range cases {
+		f := readTestData("uri_variants.m3u8")
+		u, err := SelectVariant(bufio.NewReader(f), c.pref)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u != c.expected {
+			t.Errorf("expected %s, but %s", c.expected, u)
+		}
+	}
+
+	if _, err := SelectVariant(readTestData("chunklist.m3u8"), VariantFirst); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
 func TestGetChunklist(t *testing.T) {
 	input := bufio.NewReader(readTestData("chunklist.m3u8"))
 	chunklist, err := GetChunklist(input)
@@ -41,3 +70,113 @@ func TestGetChunklist(t *testing.T) {
 		t.Error("chunklist is empty.")
 	}
 }
+
+func TestGetSegments(t *testing.T) {
+	input := bufio.NewReader(readTestData("chunklist.m3u8"))
+	segments, err := GetSegments(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) == 0 {
+		t.Fatal("segments is empty.")
+	}
+
+	s := segments[0]
+	if expected := 5 * time.Second; expected != s.Duration {
+		t.Errorf("expected %s, but %s", expected, s.Duration)
+	}
+	expected := time.Date(2016, 11, 5, 16, 0, 0, 0, time.UTC)
+	if !s.ProgramDateTime.Equal(expected) {
+		t.Errorf("expected %s, but %s", expected, s.ProgramDateTime)
+	}
+}
+
+func TestTotalDuration(t *testing.T) {
+	segments := []Segment{
+		{Duration: 5 * time.Second},
+		{Duration: 5 * time.Second},
+		{Duration: 2500 * time.Millisecond},
+	}
+	if expected, actual := 12500*time.Millisecond, TotalDuration(segments); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+	if actual := TotalDuration(nil); actual != 0 {
+		t.Errorf("expected 0, but %s", actual)
+	}
+}
+
+func TestGetSegments_Discontinuity(t *testing.T) {
+	input := bufio.NewReader(readTestData("chunklist_discontinuity.m3u8"))
+	segments, err := GetSegments(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 4; len(segments) != expected {
+		t.Fatalf("expected %d segments, but %d", expected, len(segments))
+	}
+	for i, s := range segments {
+		if expected := i == 2; expected != s.Discontinuity {
+			t.Errorf("segment %d: expected discontinuity %v, but %v", i, expected, s.Discontinuity)
+		}
+	}
+
+	parts := SplitAtDiscontinuity(segments)
+	if expected := 2; len(parts) != expected {
+		t.Fatalf("expected %d parts, but %d", expected, len(parts))
+	}
+	if parts[0][0].URI != segments[0].URI || parts[1][0].URI != segments[2].URI {
+		t.Errorf("expected the boundary at %s, but %s", segments[2].URI, parts[1][0].URI)
+	}
+}
+
+func TestGetSegments_Key(t *testing.T) {
+	const playlist = `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:5
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-KEY:METHOD=AES-128,URI="https://radiko.jp/key",IV=0x000102030405060708090a0b0c0d0e0f
+#EXTINF:5,
+1.aac
+#EXT-X-KEY:METHOD=AES-128,URI="https://radiko.jp/key2"
+#EXTINF:5,
+2.aac
+#EXTINF:5,
+3.aac
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:5,
+4.aac
+#EXT-X-ENDLIST
+`
+	segments, err := GetSegments(strings.NewReader(playlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 4; len(segments) != expected {
+		t.Fatalf("expected %d segments, but %d", expected, len(segments))
+	}
+
+	cases := []struct {
+		uri string
+		iv  string
+	}{
+		{"https://radiko.jp/key", "000102030405060708090a0b0c0d0e0f"},
+		{"https://radiko.jp/key2", "0000000000000000000000000000000b"},
+		{"https://radiko.jp/key2", "0000000000000000000000000000000c"},
+	}
+	for i, c := range cases {
+		key := segments[i].Key
+		if key == nil {
+			t.Errorf("segment %d: expected a key", i)
+			continue
+		}
+		if key.Method != "AES-128" || key.URI != c.uri {
+			t.Errorf("segment %d: unexpected key %+v", i, key)
+		}
+		if actual := hex.EncodeToString(key.IV); c.iv != actual {
+			t.Errorf("segment %d: expected %s, but %s", i, c.iv, actual)
+		}
+	}
+	if segments[3].Key != nil {
+		t.Errorf("expected no key, but %+v", segments[3].Key)
+	}
+}
@@ -1,6 +1,10 @@
 package util
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
 
 const (
 	dateLayout     = "20060102"
@@ -22,6 +26,17 @@ func init() {
 	}
 }
 
+// Location returns the timezone radiko uses, Asia/Tokyo.
+func Location() *time.Location {
+	return location
+}
+
+// JST returns the Japan Standard Time location, Asia/Tokyo.
+// It is the same as Location.
+func JST() *time.Location {
+	return Location()
+}
+
 // Date returns a textual representation of the time value
 // formatted in dateLayout.
 func Date(t time.Time) string {
@@ -31,18 +46,61 @@ func Date(t time.Time) string {
 
 // Datetime returns a textual representation of the time value
 // formatted in datetimeLayout.
+// ParseRadikoTime parses it back.
 func Datetime(t time.Time) string {
 	localTime := t.In(location)
 	return localTime.Format(datetimeLayout)
 }
 
-// ProgramsDate returns a textual representation of the time value
-// formatted in dateLayout.
+// ProgramsDate returns a textual representation of the broadcast date
+// of the time value formatted in dateLayout.
+// t is converted to Asia/Tokyo, so it may be in any location.
 func ProgramsDate(t time.Time) string {
+	return BroadcastDate(t).Format(dateLayout)
+}
+
+// BroadcastDate returns the time value of the broadcast day which t belongs to.
+// A broadcast day starts at 5:00 AM, so times before 5:00 AM
+// belong to the previous day.
+func BroadcastDate(t time.Time) time.Time {
 	localTime := t.In(location)
 	h := localTime.Hour()
 	if h >= 0 && h <= 4 {
 		localTime = localTime.Add(-24 * time.Hour)
 	}
-	return localTime.Format(dateLayout)
+	return localTime
+}
+
+// ParseDatetime is the inverse of Datetime.
+// It is the same as ParseRadikoTime.
+func ParseDatetime(s string) (time.Time, error) {
+	return ParseRadikoTime(s)
+}
+
+// ParseRadikoTime parses a 14-digit radiko timestamp formatted in
+// datetimeLayout and returns the time value in Asia/Tokyo timezone.
+// The hours from 24, which radiko uses for programs after midnight,
+// roll into the next day.
+func ParseRadikoTime(s string) (time.Time, error) {
+	if len(s) != len(datetimeLayout) {
+		return time.Time{}, fmt.Errorf("invalid radiko time: %s", s)
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return time.Time{}, fmt.Errorf("invalid radiko time: %s", s)
+		}
+	}
+
+	var days int
+	h, _ := strconv.Atoi(s[8:10])
+	if h >= 24 {
+		days = h / 24
+		s = fmt.Sprintf("%s%02d%s", s[:8], h%24, s[10:])
+	}
+
+	t, err := time.ParseInLocation(datetimeLayout, s, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.AddDate(0, 0, days), nil
 }
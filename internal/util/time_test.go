@@ -40,3 +40,113 @@ func TestProgramsDate(t *testing.T) {
 		t.Errorf("expected %s, but %s", expected, pDate)
 	}
 }
+
+func TestJST(t *testing.T) {
+	if JST() != Location() {
+		t.Error("JST should be the same as Location.")
+	}
+}
+
+func TestUTCInput(t *testing.T) {
+	// 2024-01-15 21:30 UTC is 2024-01-16 06:30 JST.
+	utc := time.Date(2024, 1, 15, 21, 30, 0, 0, time.UTC)
+	if expected, actual := "20240116063000", Datetime(utc); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+	if expected, actual := "20240116", ProgramsDate(utc); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	parsed, err := ParseRadikoTime(Datetime(utc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Equal(utc) || parsed.Location() != Location() {
+		t.Errorf("expected %v, but %v", utc, parsed)
+	}
+
+	// 2024-01-15 18:30 UTC is 2024-01-16 03:30 JST, on the broadcast day of 15th.
+	utc = time.Date(2024, 1, 15, 18, 30, 0, 0, time.UTC)
+	if expected, actual := "20240115", ProgramsDate(utc); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+}
+
+func TestParseRadikoTime(t *testing.T) {
+	n := time.Now().Truncate(time.Second)
+	parsed, err := ParseRadikoTime(Datetime(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Equal(n) {
+		t.Errorf("expected %v, but %v", n, parsed)
+	}
+
+	cases := []struct {
+		s        string
+		expected time.Time
+	}{
+		{"20240115050000", time.Date(2024, 1, 15, 5, 0, 0, 0, location)},
+		{"20240115235959", time.Date(2024, 1, 15, 23, 59, 59, 0, location)},
+		{"20240115240000", time.Date(2024, 1, 16, 0, 0, 0, 0, location)},
+		{"20240115250000", time.Date(2024, 1, 16, 1, 0, 0, 0, location)},
+		{"20240131283000", time.Date(2024, 2, 1, 4, 30, 0, 0, location)},
+		{"20241231290000", time.Date(2025, 1, 1, 5, 0, 0, 0, location)},
+	}
+	for _, c := range cases {
+		actual, err := ParseRadikoTime(c.s)
+		if err != nil {
+			t.Errorf("%s: %s", c.s, err)
+			continue
+		}
+		if !actual.Equal(c.expected) {
+			t.Errorf("%s: expected %v, but %v", c.s, c.expected, actual)
+		}
+		if actual.Location() != location {
+			t.Errorf("%s: expected %s, but %s", c.s, location, actual.Location())
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"2016111222",
+		"201611122200000",
+		"2016111222000a",
+		"+2016111220000",
+		"20161332220000",
+		"20161112226000",
+	} {
+		if _, err := ParseRadikoTime(s); err == nil {
+			t.Errorf("%s: Should detect an error.", s)
+		}
+	}
+}
+
+func TestBroadcastDate(t *testing.T) {
+	date := time.Date(2016, 11, 19, 1, 0, 0, 0, location)
+	if expected, actual := time.Friday, BroadcastDate(date).Weekday(); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	date = time.Date(2016, 11, 19, 5, 0, 0, 0, location)
+	if expected, actual := time.Saturday, BroadcastDate(date).Weekday(); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+}
+
+func TestParseDatetime(t *testing.T) {
+	n := time.Now().Truncate(time.Second)
+	parsed, err := ParseDatetime(Datetime(n.UTC()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Equal(n) || parsed.Location() != Location() {
+		t.Errorf("expected %v, but %v", n, parsed)
+	}
+
+	for _, s := range []string{"", "2024011525000"} {
+		if _, err := ParseDatetime(s); err == nil {
+			t.Errorf("%s: Should detect an error.", s)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package radiko
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+// progJSON has the fields of Prog without its methods.
+type progJSON Prog
+
+// progTimesJSON has the parsed times emitted alongside the radiko's strings.
+type progTimesJSON struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+// In addition to the fields, the start and end times are emitted
+// in RFC3339 as "start" and "end". They are omitted if not parsable.
+func (p Prog) MarshalJSON() ([]byte, error) {
+	var times progTimesJSON
+	if t, err := p.StartTime(); err == nil {
+		times.Start = t.Format(time.RFC3339)
+	}
+	if t, err := p.EndTime(); err == nil {
+		times.End = t.Format(time.RFC3339)
+	}
+
+	return json.Marshal(struct {
+		progJSON
+		progTimesJSON
+	}{progJSON(p), times})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// The start and end times are taken from the radiko's strings,
+// or from the RFC3339 times if the strings are missing.
+// The RFC3339 times are also accepted as "start_time" and "end_time".
+func (p *Prog) UnmarshalJSON(b []byte) error {
+	var v struct {
+		progJSON
+		progTimesJSON
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	prog := Prog(v.progJSON)
+	var err error
+	if prog.Ft, err = radikoTimeJSON(prog.Ft, v.Start); err != nil {
+		return err
+	}
+	if prog.To, err = radikoTimeJSON(prog.To, v.End); err != nil {
+		return err
+	}
+	*p = prog
+	return nil
+}
+
+// radikoTimeJSON returns raw if it is not empty, otherwise rfc3339,
+// converted to the radiko's format if it is in RFC3339.
+func radikoTimeJSON(raw, rfc3339 string) (string, error) {
+	s := raw
+	if s == "" {
+		s = rfc3339
+	}
+	if s == "" {
+		return "", nil
+	}
+	if _, err := util.ParseRadikoTime(s); err == nil {
+		return s, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "", err
+	}
+	return util.Datetime(t), nil
+}
@@ -0,0 +1,75 @@
+package radiko
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestProg_MarshalJSON(t *testing.T) {
+	p := Prog{
+		ID:    "10002",
+		Ft:    "20161112220000",
+		To:    "20161113000000",
+		Title: "ライムスター宇多丸のウィークエンド・シャッフル",
+		Pfm:   "宇多丸",
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`"start_time":"20161112220000"`,
+		`"end_time":"20161113000000"`,
+		`"performer":"宇多丸"`,
+		`"start":"2016-11-12T22:00:00+09:00"`,
+		`"end":"2016-11-13T00:00:00+09:00"`,
+	} {
+		if !strings.Contains(string(b), expected) {
+			t.Errorf("expected %s in %s", expected, b)
+		}
+	}
+
+	var decoded Prog
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Ft != p.Ft || decoded.To != p.To || decoded.Pfm != p.Pfm {
+		t.Errorf("expected %v, but %v", p, decoded)
+	}
+}
+
+func TestProg_UnmarshalJSON_RFC3339(t *testing.T) {
+	var p Prog
+	err := json.Unmarshal([]byte(`{"id":"1","start":"2016-11-12T13:00:00Z","end_time":"2016-11-13T00:00:00+09:00"}`), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "20161112220000"; expected != p.Ft {
+		t.Errorf("expected %s, but %s", expected, p.Ft)
+	}
+	if expected := "20161113000000"; expected != p.To {
+		t.Errorf("expected %s, but %s", expected, p.To)
+	}
+
+	if err := json.Unmarshal([]byte(`{"start_time":"tonight"}`), &p); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestStation_MarshalJSON(t *testing.T) {
+	s := Station{ID: "TBS", Name: "TBSラジオ", Progs: Progs{Progs: []Prog{{ID: "10001"}}}}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Station
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != s.ID || len(decoded.Progs.Progs) != 1 || decoded.Progs.Progs[0].ID != "10001" {
+		t.Errorf("expected %v, but %v", s, decoded)
+	}
+}
@@ -3,20 +3,68 @@ package radiko
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"strconv"
 	"strings"
 )
 
-// Login returns the Statuser that has StatusCode method.
+// Login logs in as the premium member, and returns the Statuser
+// that has StatusCode method.
+// The session is kept in the cookie jar of the Client.
+// If the credentials are rejected, it returns LoginNG and an error
+// wrapping ErrAuthFailed.
+// If the account is not a premium member, it returns LoginOK and an error
+// wrapping ErrPremiumRequired. The session is kept in this case.
 func (c *Client) Login(ctx context.Context, mail, password string) (Statuser, error) {
 	err := c.login(ctx, mail, password)
 	if err != nil {
 		return nil, err
 	}
 
-	return c.loginCheck(ctx)
+	status, err := c.loginCheck(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch s := status.(type) {
+	case LoginNG:
+		return s, fmt.Errorf("%w: %s", ErrAuthFailed, s.Message)
+	case LoginOK:
+		if s.PaidMember != "1" {
+			return s, ErrPremiumRequired
+		}
+	}
+	return status, nil
+}
+
+// Logout logs out the premium member,
+// and drops the session from the cookie jar of the Client.
+func (c *Client) Logout(ctx context.Context) error {
+	apiEndpoint := "ap/member/webapi/member/logout"
+	req, err := c.newRequest(ctx, "POST", apiEndpoint, &Params{})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return readAPIError(resp)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	c.SetJar(jar)
+	return nil
 }
 
 func (c *Client) login(ctx context.Context, mail, password string) error {
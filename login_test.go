@@ -2,6 +2,9 @@ package radiko
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
 	"testing"
 )
 
@@ -17,11 +20,75 @@ func TestLogin_StatusCode400(t *testing.T) {
 	resp, err := client.Login(context.Background(),
 		"test_mail", "test_pass")
 
-	if err != nil {
-		t.Error(err)
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected %s, but %v", ErrAuthFailed, err)
 	}
 	expected := 400
 	if actual := resp.StatusCode(); expected != actual {
 		t.Errorf("expected %d, but %d.", expected, actual)
 	}
 }
+
+func newLoginTestClient(t *testing.T, paidMember string) (*Client, func()) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ap/member/login/login":
+			if r.FormValue("pass") == "valid" {
+				http.SetCookie(w, &http.Cookie{Name: "radiko_session", Value: "session", Path: "/"})
+			}
+		case "/ap/member/webapi/member/login/check":
+			if _, err := r.Cookie("radiko_session"); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"status":"400","message":"invalid mail or password","cause":"login"}`))
+				return
+			}
+			w.Write([]byte(`{"status":"200","user_key":"key","paid_member":"` + paidMember + `","areafree":"` + paidMember + `"}`))
+		case "/ap/member/webapi/member/logout":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		closer()
+		t.Fatal(err)
+	}
+	c.SetJar(jar)
+	return c, closer
+}
+
+func TestLogin(t *testing.T) {
+	c, closer := newLoginTestClient(t, "1")
+	defer closer()
+
+	status, err := c.Login(context.Background(), "mail", "valid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 200; status.StatusCode() != expected {
+		t.Errorf("expected %d, but %d", expected, status.StatusCode())
+	}
+	if len(c.Jar().Cookies(c.URL)) == 0 {
+		t.Error("The session should be kept in the jar.")
+	}
+
+	if err := c.Logout(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if cookies := c.Jar().Cookies(c.URL); len(cookies) != 0 {
+		t.Errorf("expected no cookies, but %v", cookies)
+	}
+}
+
+func TestLogin_Errors(t *testing.T) {
+	c, closer := newLoginTestClient(t, "0")
+	defer closer()
+
+	if _, err := c.Login(context.Background(), "mail", "invalid"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected %s, but %v", ErrAuthFailed, err)
+	}
+	if _, err := c.Login(context.Background(), "mail", "valid"); !errors.Is(err, ErrPremiumRequired) {
+		t.Errorf("expected %s, but %v", ErrPremiumRequired, err)
+	}
+}
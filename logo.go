@@ -0,0 +1,65 @@
+package radiko
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+)
+
+const logoSize = "224x100"
+
+// StationLogoDataURL returns the station's logo as a base64 data URL.
+// The result is cached per station.
+func (c *Client) StationLogoDataURL(ctx context.Context, stationID string) (string, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	dataURL, ok := c.logos[stationID]
+	c.mu.Unlock()
+	if ok {
+		return dataURL, nil
+	}
+
+	apiEndpoint := path.Join(apiV2, "static/station/logo", stationID,
+		fmt.Sprintf("%s.png", logoSize))
+	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", readAPIError(resp)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(b))
+	}
+	dataURL = "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(b)
+
+	c.mu.Lock()
+	if c.logos == nil {
+		c.logos = make(map[string]string)
+	}
+	c.logos[stationID] = dataURL
+	c.mu.Unlock()
+
+	return dataURL, nil
+}
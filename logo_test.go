@@ -0,0 +1,59 @@
+package radiko
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStationLogoDataURL(t *testing.T) {
+	var requests int
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.ServeFile(w, r, filepath.Join(testdataDir, "logo.png"))
+	}))
+	defer closer()
+
+	dataURL, err := c.StationLogoDataURL(context.Background(), "TBS")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const prefix = "data:image/png;base64,"
+	if !strings.HasPrefix(dataURL, prefix) {
+		t.Fatalf("invalid data url: %s", dataURL)
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(dataURL, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadFile(filepath.Join(testdataDir, "logo.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, b) {
+		t.Error("decoded logo does not match the fixture.")
+	}
+
+	if _, err = c.StationLogoDataURL(context.Background(), "TBS"); err != nil {
+		t.Error(err)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, but %d", requests)
+	}
+}
+
+func TestStationLogoDataURL_EmptyStationID(t *testing.T) {
+	c, closer := newTestClient(t, http.NotFoundHandler())
+	defer closer()
+
+	_, err := c.StationLogoDataURL(context.Background(), "")
+	if err == nil {
+		t.Error("Should detect an error.")
+	}
+}
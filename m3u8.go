@@ -1,7 +1,14 @@
 package radiko
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"github.com/chikulla/go-radiko/internal/m3u8"
 )
@@ -16,3 +23,111 @@ func GetChunklistFromM3U8(uri string) ([]string, error) {
 
 	return m3u8.GetChunklist(resp.Body)
 }
+
+// getSegments returns the media segments in the chunklist.
+// Like VerifyPlaylist, it maps 404, 410 and 403 to ErrTimeshiftExpired and ErrAreaRestricted.
+// The relative urls of the segments and the keys are resolved against uri.
+func (c *Client) getSegments(ctx context.Context, uri string) ([]m3u8.Segment, error) {
+	base, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.getM3U8(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	segments, err := m3u8.GetSegments(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	for i := range segments {
+		u, err := base.Parse(segments[i].URI)
+		if err != nil {
+			return nil, err
+		}
+		segments[i].URI = u.String()
+
+		if key := segments[i].Key; key != nil {
+			u, err := base.Parse(key.URI)
+			if err != nil {
+				return nil, err
+			}
+			key.URI = u.String()
+		}
+	}
+	return segments, nil
+}
+
+func (c *Client) getM3U8(ctx context.Context, uri string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", c.getUserAgent())
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := playlistStatusError(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// playlistStatusError returns the error for the status code of the playlist.
+// If radiko responds with 404 or 410, it returns ErrTimeshiftExpired,
+// and with 403, it returns ErrAreaRestricted.
+func playlistStatusError(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return fmt.Errorf("%w: %s", ErrTimeshiftExpired, readAPIError(resp))
+	case resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: %s", ErrAreaRestricted, readAPIError(resp))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return readAPIError(resp)
+	}
+	return nil
+}
+
+// m3u8Header is the tag which a playlist starts with.
+var m3u8Header = []byte("#EXTM3U")
+
+// VerifyPlaylist checks that the playlist at uri, like the one returned by
+// TimeshiftPlaylistM3U8, is reachable before downloading it.
+// Only the beginning of the playlist is read.
+// If radiko responds with 404 or 410, it returns ErrTimeshiftExpired,
+// and with 403, it returns ErrAreaRestricted.
+func (c *Client) VerifyPlaylist(ctx context.Context, uri string) error {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", c.getUserAgent())
+	req.Header.Set(radikoAuthTokenHeader, c.AuthToken())
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := playlistStatusError(resp); err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(len(utf8BOM)+len(m3u8Header))))
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(bytes.TrimPrefix(b, utf8BOM), m3u8Header) {
+		return errors.New("invalid playlist")
+	}
+	return nil
+}
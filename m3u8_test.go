@@ -1,6 +1,10 @@
 package radiko
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
 	"testing"
 )
 
@@ -10,3 +14,88 @@ func TestGetChunklistFromM3U8(t *testing.T) {
 		t.Error("Should detect an error.")
 	}
 }
+
+func TestVerifyPlaylist(t *testing.T) {
+	var token string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get(radikoAuthTokenHeader)
+		switch r.URL.Path {
+		case "/chunklist.m3u8":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "chunklist.m3u8"))
+		case "/expired.m3u8":
+			http.NotFound(w, r)
+		case "/restricted.m3u8":
+			w.WriteHeader(http.StatusForbidden)
+		case "/error.m3u8":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte("<html></html>"))
+		}
+	}))
+	defer closer()
+	c.setAuthTokenHeader("token")
+
+	ctx := context.Background()
+	base := c.URL.String()
+	if err := c.VerifyPlaylist(ctx, base+"/chunklist.m3u8"); err != nil {
+		t.Error(err)
+	}
+	if expected := "token"; expected != token {
+		t.Errorf("expected %s, but %s", expected, token)
+	}
+
+	cases := []struct {
+		path     string
+		expected error
+	}{
+		{"/expired.m3u8", ErrTimeshiftExpired},
+		{"/restricted.m3u8", ErrAreaRestricted},
+	}
+	for _, cs := range cases {
+		if err := c.VerifyPlaylist(ctx, base+cs.path); !errors.Is(err, cs.expected) {
+			t.Errorf("%s: expected %s, but %v", cs.path, cs.expected, err)
+		}
+	}
+
+	var apiErr *APIError
+	if err := c.VerifyPlaylist(ctx, base+"/error.m3u8"); !errors.As(err, &apiErr) {
+		t.Errorf("expected APIError, but %v", err)
+	}
+	if err := c.VerifyPlaylist(ctx, base+"/invalid.m3u8"); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestGetSegments_Status(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/expired.m3u8":
+			http.NotFound(w, r)
+		case "/restricted.m3u8":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer closer()
+
+	ctx := context.Background()
+	base := c.URL.String()
+	cases := []struct {
+		path     string
+		expected error
+	}{
+		{"/expired.m3u8", ErrTimeshiftExpired},
+		{"/restricted.m3u8", ErrAreaRestricted},
+	}
+	for _, cs := range cases {
+		if _, err := c.getSegments(ctx, base+cs.path); !errors.Is(err, cs.expected) {
+			t.Errorf("%s: expected %s, but %v", cs.path, cs.expected, err)
+		}
+	}
+
+	var apiErr *APIError
+	if _, err := c.getSegments(ctx, base+"/error.m3u8"); !errors.As(err, &apiErr) {
+		t.Errorf("expected APIError, but %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build online
+// +build online
+
+package radiko
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOnline_GetStations(t *testing.T) {
+	c := newOnlineTestClient(t)
+
+	stations, err := c.GetStations(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) == 0 {
+		t.Error("Stations is nil.")
+	}
+}
+
+func TestOnline_GetWeeklyPrograms(t *testing.T) {
+	c := newOnlineTestClient(t)
+
+	stations, err := c.GetWeeklyPrograms(context.Background(), "LFR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) == 0 {
+		t.Error("Stations is nil.")
+	}
+
+	_, err = c.GetWeeklyPrograms(context.Background(), "INVALID")
+	if err != ErrStationNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
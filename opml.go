@@ -0,0 +1,53 @@
+package radiko
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+)
+
+type opml struct {
+	XMLName  xml.Name      `xml:"opml"`
+	Version  string        `xml:"version,attr"`
+	Title    string        `xml:"head>title"`
+	Outlines []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Type   string `xml:"type,attr"`
+	Text   string `xml:"text,attr"`
+	Title  string `xml:"title,attr"`
+	XMLURL string `xml:"xmlUrl,attr"`
+}
+
+// ToOPML writes an OPML outline of the stations to w.
+// Each station has an outline whose feed url is feedBaseURL/{stationID}.
+func (rs RadioStations) ToOPML(w io.Writer, feedBaseURL string) error {
+	if feedBaseURL == "" {
+		return errors.New("feedBaseURL is empty")
+	}
+	base := strings.TrimSuffix(feedBaseURL, "/")
+
+	doc := opml{
+		Version:  "2.0",
+		Title:    "radiko stations",
+		Outlines: make([]opmlOutline, 0, len(rs)),
+	}
+	for _, s := range rs {
+		doc.Outlines = append(doc.Outlines, opmlOutline{
+			Type:   "rss",
+			Text:   s.Name,
+			Title:  s.Name,
+			XMLURL: base + "/" + url.PathEscape(s.ID),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
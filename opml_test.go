@@ -0,0 +1,44 @@
+package radiko
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestRadioStations_ToOPML(t *testing.T) {
+	stations := RadioStations{
+		{ID: "TBS", Name: "TBSラジオ"},
+		{ID: "QRR", Name: "文化放送"},
+		{ID: "LFR", Name: "ニッポン放送"},
+	}
+
+	var buf bytes.Buffer
+	if err := stations.ToOPML(&buf, "https://example.com/feeds/"); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc opml
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Outlines) != len(stations) {
+		t.Fatalf("expected %d outlines, but %d", len(stations), len(doc.Outlines))
+	}
+	for i, s := range stations {
+		o := doc.Outlines[i]
+		if o.Text != s.Name {
+			t.Errorf("expected %s, but %s", s.Name, o.Text)
+		}
+		if expected := "https://example.com/feeds/" + s.ID; expected != o.XMLURL {
+			t.Errorf("expected %s, but %s", expected, o.XMLURL)
+		}
+	}
+}
+
+func TestRadioStations_ToOPML_EmptyFeedBaseURL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (RadioStations{}).ToOPML(&buf, ""); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
@@ -0,0 +1,242 @@
+package radiko
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Option configures a Client.
+type Option func(*Client) error
+
+// WithHTTPClient sets the HTTP client used by the Client.
+// The client is copied, so its timeout, proxy and transport are shared,
+// but the cookie jar of the Client is used if it has no jar.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) error {
+		if hc == nil {
+			return errors.New("httpClient is nil")
+		}
+
+		cp := *hc
+		if cp.Jar == nil && c.httpClient != nil {
+			cp.Jar = c.httpClient.Jar
+		}
+		c.httpClient = &cp
+		return nil
+	}
+}
+
+// WithBaseURL sets the endpoint of the radiko API.
+// It is useful to send requests to a fake server in tests.
+func WithBaseURL(rawurl string) Option {
+	return func(c *Client) error {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid base url: %s", rawurl)
+		}
+		c.URL = u
+		return nil
+	}
+}
+
+// WithAreaID sets the areaID of the Client
+// instead of detecting it from the current IP.
+// It returns ErrInvalidAreaID if the areaID is not one of JP1 to JP47.
+func WithAreaID(areaID string) Option {
+	return func(c *Client) error {
+		return c.SetAreaID(areaID)
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests.
+// It overrides the default set by SetUserAgent.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) error {
+		if ua == "" {
+			return errors.New("User-Agent is empty")
+		}
+		c.userAgent = ua
+		return nil
+	}
+}
+
+// WithAppVersion sets the X-Radiko-App-Version header of the requests.
+// The default is the version of the official web player.
+func WithAppVersion(version string) Option {
+	return func(c *Client) error {
+		if version == "" {
+			return errors.New("app version is empty")
+		}
+		c.appVersion = version
+		return nil
+	}
+}
+
+// WithAutoReauth sets whether the auth flow is run again
+// when the auth token is rejected. It is enabled by default.
+func WithAutoReauth(enabled bool) Option {
+	return func(c *Client) error {
+		c.autoReauth = enabled
+		return nil
+	}
+}
+
+// RequestLogger is called after each HTTP request with its result.
+// status is zero if no response is received.
+type RequestLogger func(ctx context.Context, method, url string, status int, dur time.Duration, err error)
+
+// WithRequestLogger sets the logger called after each HTTP request
+// including the retries.
+// The headers and the bodies are not passed to it,
+// so the auth token and the credentials are not logged.
+func WithRequestLogger(logger RequestLogger) Option {
+	return func(c *Client) error {
+		if logger == nil {
+			return errors.New("logger is nil")
+		}
+		c.requestLogger = logger
+		return nil
+	}
+}
+
+// WithRetry retries the GET and HEAD requests on the network errors
+// and 5xx responses, up to maxAttempts times in total.
+// The delay between the attempts starts at baseDelay and doubles with jitter.
+// The other requests like POST are not retried.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) error {
+		if maxAttempts < 1 {
+			return errors.New("maxAttempts must be positive")
+		}
+		if baseDelay < 0 {
+			return errors.New("baseDelay must not be negative")
+		}
+		c.retryAttempts = maxAttempts
+		c.retryBaseDelay = baseDelay
+		return nil
+	}
+}
+
+// WithMaxConcurrency sets the max number of concurrent requests
+// sent by the methods that fetch multiple resources.
+// It overrides SetMaxConcurrency for the Client.
+func WithMaxConcurrency(n int) Option {
+	return func(c *Client) error {
+		if n <= 0 {
+			return errors.New("max concurrency must be positive")
+		}
+		c.maxConcurrency = n
+		return nil
+	}
+}
+
+// WithRequestTimeout sets the timeout of each HTTP request,
+// including reading its body. Each retry has its own timeout.
+// A tighter deadline of the caller's context is still respected.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *Client) error {
+		if d <= 0 {
+			return errors.New("request timeout must be positive")
+		}
+		c.requestTimeout = d
+		return nil
+	}
+}
+
+// WithRateLimit limits the requests of the Client by a token bucket
+// which refills at r tokens per second and holds up to b tokens.
+// The requests wait for a token until their context is done.
+func WithRateLimit(r rate.Limit, b int) Option {
+	return func(c *Client) error {
+		if r <= 0 || b <= 0 {
+			return errors.New("rate limit must be positive")
+		}
+		c.limiter = rate.NewLimiter(r, b)
+		return nil
+	}
+}
+
+// WithCache caches the station lists and the program schedules for ttl.
+// The schedules of the past dates never expire, since they are immutable.
+// The cached values are copied, so the results can be modified.
+func WithCache(ttl time.Duration) Option {
+	return func(c *Client) error {
+		if ttl <= 0 {
+			return errors.New("cache ttl must be positive")
+		}
+		c.cache = newCache(ttl)
+		return nil
+	}
+}
+
+// WithTextNormalization sets whether the HTML entities in Title, SubTitle,
+// Desc, Pfm and Info of the programs are unescaped on decoding.
+// It is disabled by default to keep the raw data.
+func WithTextNormalization(enabled bool) Option {
+	return func(c *Client) error {
+		c.normalizeText = enabled
+		return nil
+	}
+}
+
+// WithNFKCNormalization sets whether the full-width and half-width characters
+// in the text of the programs are unified by NFKC on decoding.
+// It is applied after the unescaping by WithTextNormalization, and is disabled by default.
+func WithNFKCNormalization(enabled bool) Option {
+	return func(c *Client) error {
+		c.normalizeNFKC = enabled
+		return nil
+	}
+}
+
+// WithListedStreamURLs sets whether the urls to create the playlists
+// listed in the station list are used instead of the default endpoints.
+// Looking up the url costs a request of the station list
+// unless it is cached by WithCache or WarmStationDirectory.
+// The auth token is sent with the request, so a listed url is used
+// only if it is on the host of the default endpoint,
+// that is, the Client's URL for timeshift.
+// It is disabled by default.
+func WithListedStreamURLs(enabled bool) Option {
+	return func(c *Client) error {
+		c.listedStreamURLs = enabled
+		return nil
+	}
+}
+
+// WithTransportTuning tunes the connection reuse of the Client's transport
+// for the batch workloads.
+// The transport is cloned, so the other clients are not affected.
+// HTTP/2 is attempted for the https endpoints.
+func WithTransportTuning(maxIdleConns, maxIdleConnsPerHost int, idleConnTimeout time.Duration) Option {
+	return func(c *Client) error {
+		if maxIdleConns < 0 || maxIdleConnsPerHost < 0 || idleConnTimeout < 0 {
+			return errors.New("transport tuning must not be negative")
+		}
+
+		base, ok := c.httpClient.Transport.(*http.Transport)
+		if !ok || base == nil {
+			base = http.DefaultTransport.(*http.Transport)
+		}
+		tr := base.Clone()
+		tr.MaxIdleConns = maxIdleConns
+		tr.MaxIdleConnsPerHost = maxIdleConnsPerHost
+		tr.IdleConnTimeout = idleConnTimeout
+		tr.ForceAttemptHTTP2 = true
+
+		hc := *c.httpClient
+		hc.Transport = tr
+		c.httpClient = &hc
+		c.transport = tr
+		return nil
+	}
+}
@@ -0,0 +1,366 @@
+package radiko
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestWithTransportTuning(t *testing.T) {
+	c := &Client{httpClient: &http.Client{Timeout: defaultHTTPTimeout}}
+	if err := WithTransportTuning(64, 16, time.Minute)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("unexpected transport: %T", c.httpClient.Transport)
+	}
+	if tr.MaxIdleConns != 64 || tr.MaxIdleConnsPerHost != 16 || tr.IdleConnTimeout != time.Minute {
+		t.Errorf("transport is not tuned: %d, %d, %s",
+			tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout)
+	}
+	if tr == http.DefaultTransport {
+		t.Error("DefaultTransport should not be modified.")
+	}
+	if c.httpClient.Timeout != defaultHTTPTimeout {
+		t.Errorf("expected %s, but %s", defaultHTTPTimeout, c.httpClient.Timeout)
+	}
+
+	if err := WithTransportTuning(-1, 0, 0)(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{httpClient: &http.Client{Jar: jar}}
+
+	tr := &http.Transport{}
+	hc := &http.Client{Timeout: time.Second, Transport: tr}
+	if err := WithHTTPClient(hc)(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.httpClient.Transport != tr {
+		t.Error("transport should be shared.")
+	}
+	if c.httpClient.Timeout != time.Second {
+		t.Errorf("expected %s, but %s", time.Second, c.httpClient.Timeout)
+	}
+	if c.httpClient.Jar != jar {
+		t.Error("cookie jar should be kept.")
+	}
+	if hc.Jar != nil {
+		t.Error("the given client should not be modified.")
+	}
+
+	if err := WithHTTPClient(nil)(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func benchmarkBatchFetch(b *testing.B, opts ...Option) {
+	var conns int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := &Client{URL: u, httpClient: &http.Client{Transport: &http.Transport{}}, maxConcurrency: 16}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			b.Fatal(err)
+		}
+	}
+	defer c.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := c.parallel(context.Background(), 64, func(ctx context.Context, _ int) error {
+			req, err := c.newRequest(ctx, "GET", "", &Params{})
+			if err != nil {
+				return err
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&conns))/float64(b.N), "conns/op")
+}
+
+func BenchmarkBatchFetch_Default(b *testing.B) {
+	benchmarkBatchFetch(b)
+}
+
+func BenchmarkBatchFetch_TransportTuning(b *testing.B) {
+	benchmarkBatchFetch(b, WithTransportTuning(64, 16, 90*time.Second))
+}
+
+func TestWithUserAgent(t *testing.T) {
+	var header http.Header
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer closer()
+
+	for _, opt := range []Option{WithUserAgent("test-agent/1.0"), WithAppVersion("9.9.9")} {
+		if err := opt(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := c.newRequest(context.Background(), "GET", "", &Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if expected := "test-agent/1.0"; expected != header.Get("User-Agent") {
+		t.Errorf("expected %s, but %s", expected, header.Get("User-Agent"))
+	}
+	if expected := "9.9.9"; expected != header.Get(radikoAppVersionHeader) {
+		t.Errorf("expected %s, but %s", expected, header.Get(radikoAppVersionHeader))
+	}
+
+	if err := WithUserAgent("")(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+	if err := WithAppVersion("")(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestClient_DefaultHeaders(t *testing.T) {
+	c := &Client{}
+	if c.getUserAgent() != userAgent {
+		t.Errorf("expected %s, but %s", userAgent, c.getUserAgent())
+	}
+	if c.getAppVersion() != radikoAppVersion {
+		t.Errorf("expected %s, but %s", radikoAppVersion, c.getAppVersion())
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	var requests int32
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer closer()
+	if err := WithRateLimit(rate.Every(time.Hour), 1)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := c.newRequest(context.Background(), "GET", "", &Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err = c.newRequest(ctx, "GET", "", &Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(req); err == nil {
+		t.Error("Should detect an error.")
+	}
+	if requests != 1 {
+		t.Errorf("expected 1, but %d", requests)
+	}
+
+	if err := WithRateLimit(0, 1)(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestWithBaseURL(t *testing.T) {
+	c := &Client{}
+	if err := WithBaseURL("http://127.0.0.1:8080/")(c); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "127.0.0.1:8080"; c.URL.Host != expected {
+		t.Errorf("expected %s, but %s", expected, c.URL.Host)
+	}
+	if err := WithBaseURL("radiko.jp")(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestNew_WithAreaID(t *testing.T) {
+	defer teardownHTTPClient()
+
+	c, err := New("", WithAreaID("JP27"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "JP27"; c.AreaID() != expected {
+		t.Errorf("expected %s, but %s", expected, c.AreaID())
+	}
+	if err := WithAreaID("JP48")(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestWithRequestLogger(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer closer()
+	c.setAuthTokenHeader("secret-token")
+
+	var logs []string
+	err := WithRequestLogger(func(ctx context.Context, method, url string, status int, dur time.Duration, err error) {
+		logs = append(logs, fmt.Sprintf("%s %s %d %v", method, url, status, err))
+	})(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.callWithAuthTokenHeader(context.Background(), "POST", "v2/api/ts/playlist.m3u8", &Params{
+		query: map[string]string{"station_id": "TBS"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 log, but %v", logs)
+	}
+	if expected := "POST " + c.URL.String() + "/v2/api/ts/playlist.m3u8?station_id=TBS 204 <nil>"; expected != logs[0] {
+		t.Errorf("expected %s, but %s", expected, logs[0])
+	}
+	if strings.Contains(logs[0], "secret-token") {
+		t.Errorf("auth token is logged: %s", logs[0])
+	}
+
+	if err := WithRequestLogger(nil)(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestWithRequestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-done:
+		}
+	}))
+	defer closer()
+	defer close(done)
+
+	if err := WithRequestTimeout(50 * time.Millisecond)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := c.newRequest(context.Background(), "GET", "v3/station/list/JP13.xml", &Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err = c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %s, but %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to return promptly, but %s", elapsed)
+	}
+
+	if err := WithRequestTimeout(0)(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestWithTextNormalization(t *testing.T) {
+	const prog = `<prog ft="20161112220000" to="20161113000000">
+<title>ＪＵＮＫ　ﾊﾞﾅﾅﾏﾝ</title><desc>&amp;lt;b&amp;gt;Ｑ＆Ａ&amp;lt;/b&amp;gt;</desc>
+</prog>`
+	var body string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer closer()
+
+	cases := []struct {
+		unescape, nfkc bool
+		title, desc    string
+	}{
+		{false, false, "ＪＵＮＫ　ﾊﾞﾅﾅﾏﾝ", "&lt;b&gt;Ｑ＆Ａ&lt;/b&gt;"},
+		{true, false, "ＪＵＮＫ　ﾊﾞﾅﾅﾏﾝ", "<b>Ｑ＆Ａ</b>"},
+		{false, true, "JUNK バナナマン", "&lt;b&gt;Q&A&lt;/b&gt;"},
+		{true, true, "JUNK バナナマン", "<b>Q&A</b>"},
+	}
+	for _, cs := range cases {
+		if err := WithTextNormalization(cs.unescape)(c); err != nil {
+			t.Fatal(err)
+		}
+		if err := WithNFKCNormalization(cs.nfkc)(c); err != nil {
+			t.Fatal(err)
+		}
+
+		// progs
+		body = `<radiko><stations><station id="TBS"><progs>` + prog + `</progs></station></stations></radiko>`
+		progs, err := c.GetProgramsByStation(context.Background(), "TBS", programsFixtureDate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(progs) != 1 {
+			t.Fatalf("expected 1, but %d", len(progs))
+		}
+		if progs[0].Title != cs.title || progs[0].Desc != cs.desc {
+			t.Errorf("expected %s %s, but %s %s", cs.title, cs.desc, progs[0].Title, progs[0].Desc)
+		}
+
+		// scd
+		body = `<radiko><stations><station id="TBS"><scd><progs>` + prog + `</progs></scd></station></stations></radiko>`
+		stations, err := c.GetStationsForArea(context.Background(), areaIDTokyo, programsFixtureDate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stations) != 1 || len(stations[0].Scd.Progs.Progs) != 1 {
+			t.Fatalf("expected 1 scd program, but %v", stations)
+		}
+		if p := stations[0].Scd.Progs.Progs[0]; p.Title != cs.title || p.Desc != cs.desc {
+			t.Errorf("expected %s %s, but %s %s", cs.title, cs.desc, p.Title, p.Desc)
+		}
+	}
+}
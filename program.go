@@ -1,17 +1,30 @@
 package radiko
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"path"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/chikulla/go-radiko/internal/util"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Stations is a slice of Station.
@@ -19,215 +32,1341 @@ type Stations []Station
 
 // Station is a struct.
 type Station struct {
-	ID    string `xml:"id,attr"`
-	Name  string `xml:"name"`
-	Scd   Scd    `xml:"scd,omitempty"`
-	Progs Progs  `xml:"progs,omitempty"`
+	ID    string `xml:"id,attr" json:"id"`
+	Name  string `xml:"name" json:"name"`
+	Scd   Scd    `xml:"scd,omitempty" json:"scd"`
+	Progs Progs  `xml:"progs,omitempty" json:"progs"`
+	Logos []Logo `xml:"logo" json:"logos,omitempty"`
 }
 
 type RadioStations []RadioStation
 
 type RadioStation struct {
-	ID   string `xml:"id"`
-	Name string `xml:"name"`
+	ID       string   `xml:"id" json:"id"`
+	Name     string   `xml:"name" json:"name"`
+	Areafree bool     `xml:"areafree" json:"areafree"`
+	AreaIDs  []string `xml:"area_id" json:"area_ids"`
+	Logos    []Logo   `xml:"logo" json:"logos,omitempty"`
+	// StreamURLs is empty if the station list has no stream url.
+	StreamURLs []StreamURL `xml:"url" json:"stream_urls,omitempty"`
+}
+
+// StreamURL is a url to create the playlist of the station's stream.
+type StreamURL struct {
+	PlaylistCreateURL string `xml:"playlist_create_url" json:"playlist_create_url"`
+	// Areafree is true if the url is for the areafree streaming,
+	// which requires the premium login.
+	Areafree bool `xml:"areafree,attr" json:"areafree"`
+	// Timefree is true if the url is for timeshift,
+	// and false if it is for the live stream.
+	Timefree bool `xml:"timefree,attr" json:"timefree"`
+}
+
+// playlistCreateURL returns the station's url to create the playlist
+// of timeshift if timefree is true, or of the live stream otherwise.
+// The url for the Client's area is preferred to the areafree one.
+// If the station has no such url, it returns "".
+func (rs RadioStation) playlistCreateURL(timefree bool) string {
+	var areafree string
+	for _, u := range rs.StreamURLs {
+		if u.Timefree != timefree || u.PlaylistCreateURL == "" {
+			continue
+		}
+		if !u.Areafree {
+			return u.PlaylistCreateURL
+		}
+		if areafree == "" {
+			areafree = u.PlaylistCreateURL
+		}
+	}
+	return areafree
+}
+
+// Logo is a station logo image.
+type Logo struct {
+	Width  int    `xml:"width,attr" json:"width"`
+	Height int    `xml:"height,attr" json:"height"`
+	URL    string `xml:",chardata" json:"url"`
+}
+
+// LargestLogo returns the url of the station's largest logo.
+// If the station has no logo, it returns "".
+func (rs RadioStation) LargestLogo() string {
+	return largestLogo(rs.Logos)
+}
+
+// LargestLogo returns the url of the station's largest logo.
+// If the station has no logo, it returns "".
+func (s Station) LargestLogo() string {
+	return largestLogo(s.Logos)
+}
+
+func largestLogo(logos []Logo) string {
+	var largest *Logo
+	for i := range logos {
+		if largest == nil || logos[i].Width*logos[i].Height > largest.Width*largest.Height {
+			largest = &logos[i]
+		}
+	}
+	if largest == nil {
+		return ""
+	}
+	return strings.TrimSpace(largest.URL)
+}
+
+// AvailableInArea reports whether the station is carried in the area.
+// If the station has no area coverage data, it reports true.
+func (rs RadioStation) AvailableInArea(areaID string) bool {
+	if len(rs.AreaIDs) == 0 {
+		return true
+	}
+	for _, id := range rs.AreaIDs {
+		if id == areaID {
+			return true
+		}
+	}
+	return false
+}
+
+// stationIDPattern matches the station ids like TBS, RN1 or ALPHA-STATION.
+var stationIDPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]*$`)
+
+// ValidateStationID returns ErrInvalidStationID
+// if the id is empty or not in the form of the radiko station ids.
+// It does not check whether the station exists.
+func ValidateStationID(id string) error {
+	if id == "" {
+		return fmt.Errorf("%w: StationID is empty", ErrInvalidStationID)
+	}
+	if !stationIDPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidStationID, id)
+	}
+	return nil
+}
+
+// MergeRadioStations merges the lists into one RadioStations.
+// Stations which have the same ID are included only once,
+// in the order they first appear.
+func MergeRadioStations(lists ...RadioStations) RadioStations {
+	seen := make(map[string]bool)
+
+	var merged RadioStations
+	for _, list := range lists {
+		for _, s := range list {
+			if seen[s.ID] {
+				continue
+			}
+			seen[s.ID] = true
+			merged = append(merged, s)
+		}
+	}
+	return merged
 }
 
 // Scd is a struct.
 type Scd struct {
-	Progs Progs `xml:"progs"`
+	Progs Progs `xml:"progs" json:"progs"`
 }
 
 // Progs is a slice of Prog.
 type Progs struct {
-	Date  string `xml:"date"`
-	Progs []Prog `xml:"prog"`
+	Date  string `xml:"date" json:"date"`
+	Progs []Prog `xml:"prog" json:"progs"`
 }
 
 // Prog is a struct.
 type Prog struct {
-	Ft       string `xml:"ft,attr"`
-	To       string `xml:"to,attr"`
-	Ftl      string `xml:"ftl,attr"`
-	Tol      string `xml:"tol,attr"`
-	Dur      string `xml:"dur,attr"`
-	Title    string `xml:"title"`
-	SubTitle string `xml:"sub_title"`
-	Desc     string `xml:"desc"`
-	Pfm      string `xml:"pfm"`
-	Info     string `xml:"info"`
-	URL      string `xml:"url"`
+	ID       string `xml:"id,attr" json:"id"`
+	MasterID string `xml:"master_id,attr" json:"master_id"`
+	Ft       string `xml:"ft,attr" json:"start_time"`
+	To       string `xml:"to,attr" json:"end_time"`
+	Ftl      string `xml:"ftl,attr" json:"start_hhmm"`
+	Tol      string `xml:"tol,attr" json:"end_hhmm"`
+	Dur      string `xml:"dur,attr" json:"duration"`
+	Title    string `xml:"title" json:"title"`
+	SubTitle string `xml:"sub_title" json:"sub_title"`
+	Desc     string `xml:"desc" json:"description"`
+	Pfm      string `xml:"pfm" json:"performer"`
+	Info     string `xml:"info" json:"info"`
+	URL      string `xml:"url" json:"url"`
+	ImageURL string `xml:"img" json:"image_url,omitempty"`
+	Images   Images `xml:"metas" json:"images,omitempty"`
+	// Genre is empty if the program has no genre.
+	Genre Genre `xml:"genre>program" json:"genre"`
+
+	// TsInNgStart and TsInNgEnd are the range that timeshift is not allowed.
+	TsInNgStart string `xml:"ts_in_ng_start,attr" json:"ts_in_ng_start,omitempty"`
+	TsInNgEnd   string `xml:"ts_in_ng_end,attr" json:"ts_in_ng_end,omitempty"`
+
+	// TsInNg is non-zero if timeshift is not allowed.
+	TsInNg int `xml:"ts_in_ng" json:"ts_in_ng"`
+	// TsOutNg is non-zero if sharing the timeshift is not allowed.
+	TsOutNg int `xml:"ts_out_ng" json:"ts_out_ng"`
+}
+
+// CanTimeshift reports whether the program is allowed in timeshift.
+func (p Prog) CanTimeshift() bool {
+	return p.TsInNg == 0
+}
+
+// CanShare reports whether the program's timeshift is allowed to be shared.
+func (p Prog) CanShare() bool {
+	return p.CanTimeshift() && p.TsOutNg == 0
+}
+
+// StartTime returns Ft parsed in JST.
+// The hours past 24:00 are rolled over to the next day.
+func (p Prog) StartTime() (time.Time, error) {
+	t, err := util.ParseRadikoTime(p.Ft)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ft: %w", err)
+	}
+	return t, nil
+}
+
+// EndTime returns To parsed in JST.
+// The hours past 24:00 are rolled over to the next day.
+func (p Prog) EndTime() (time.Time, error) {
+	t, err := util.ParseRadikoTime(p.To)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid to: %w", err)
+	}
+	return t, nil
+}
+
+// BroadcastDate returns the midnight in JST of the broadcast day
+// which the program belongs to.
+// A broadcast day runs from 05:00 to 29:00, so the program
+// which starts before 05:00 belongs to the previous date.
+func (p Prog) BroadcastDate() (time.Time, error) {
+	ft, err := p.StartTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	y, m, d := util.BroadcastDate(ft).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, util.Location()), nil
+}
+
+// StartClock returns Ftl formatted as HH:MM.
+// The hours past 24:00 are rendered as the time of the next day,
+// so 2500 is 01:00. If Ftl is invalid, it returns "".
+func (p Prog) StartClock() string {
+	return clock(p.Ftl)
+}
+
+// EndClock returns Tol formatted as HH:MM like StartClock.
+func (p Prog) EndClock() string {
+	return clock(p.Tol)
+}
+
+// StartOffset returns Ftl as the duration since the midnight
+// of the broadcast day. The hours past 24:00 are kept,
+// so 2500 is 25 hours and the programs are ordered in the day.
+func (p Prog) StartOffset() (time.Duration, error) {
+	d, err := parseClock(p.Ftl)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ftl: %w", err)
+	}
+	return d, nil
+}
+
+// EndOffset returns Tol as the duration since the midnight
+// of the broadcast day like StartOffset.
+func (p Prog) EndOffset() (time.Duration, error) {
+	d, err := parseClock(p.Tol)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tol: %w", err)
+	}
+	return d, nil
+}
+
+func clock(hhmm string) string {
+	d, err := parseClock(hhmm)
+	if err != nil {
+		return ""
+	}
+	m := int(d/time.Minute) % (24 * 60)
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
+
+// parseClock parses the time of day formatted as hhmm.
+// The leading zero may be omitted, like 500.
+func parseClock(hhmm string) (time.Duration, error) {
+	if len(hhmm) < 3 || len(hhmm) > 4 {
+		return 0, fmt.Errorf("invalid clock: %s", hhmm)
+	}
+	n, err := strconv.Atoi(hhmm)
+	if err != nil || n < 0 || n%100 >= 60 {
+		return 0, fmt.Errorf("invalid clock: %s", hhmm)
+	}
+	return time.Duration(n/100)*time.Hour + time.Duration(n%100)*time.Minute, nil
+}
+
+// Duration returns Dur in seconds as time.Duration.
+// If Dur is empty or zero, it returns EndTime minus StartTime.
+func (p Prog) Duration() (time.Duration, error) {
+	if p.Dur != "" {
+		sec, err := strconv.Atoi(p.Dur)
+		if err != nil {
+			return 0, fmt.Errorf("invalid dur: %s", p.Dur)
+		}
+		if sec > 0 {
+			return time.Duration(sec) * time.Second, nil
+		}
+	}
+
+	start, err := p.StartTime()
+	if err != nil {
+		return 0, fmt.Errorf("no dur and %w", err)
+	}
+	end, err := p.EndTime()
+	if err != nil {
+		return 0, fmt.Errorf("no dur and %w", err)
+	}
+	return end.Sub(start), nil
+}
+
+// TimeRange represents the range [Start, End).
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// TimeshiftableRanges returns the ranges of the program
+// which are allowed in timeshift, excluding the NG range.
+// It returns nil if Ft or To is invalid.
+func (p Prog) TimeshiftableRanges() []TimeRange {
+	ft, err := util.ParseRadikoTime(p.Ft)
+	if err != nil {
+		return nil
+	}
+	to, err := util.ParseRadikoTime(p.To)
+	if err != nil {
+		return nil
+	}
+
+	ngStart, err1 := util.ParseRadikoTime(p.TsInNgStart)
+	ngEnd, err2 := util.ParseRadikoTime(p.TsInNgEnd)
+	if err1 != nil || err2 != nil || !ngStart.Before(ngEnd) {
+		return []TimeRange{{Start: ft, End: to}}
+	}
+
+	var ranges []TimeRange
+	if ft.Before(ngStart) {
+		end := ngStart
+		if to.Before(end) {
+			end = to
+		}
+		ranges = append(ranges, TimeRange{Start: ft, End: end})
+	}
+	if ngEnd.Before(to) {
+		start := ngEnd
+		if start.Before(ft) {
+			start = ft
+		}
+		ranges = append(ranges, TimeRange{Start: start, End: to})
+	}
+	return ranges
+}
+
+// Contains reports whether t is in the range.
+func (r TimeRange) Contains(t time.Time) bool {
+	return !t.Before(r.Start) && t.Before(r.End)
+}
+
+// IsOnAir reports whether the program is on the air at now.
+// It returns false if the start or end time is invalid.
+func (p Prog) IsOnAir(now time.Time) bool {
+	ft, err := p.StartTime()
+	if err != nil {
+		return false
+	}
+	to, err := p.EndTime()
+	if err != nil {
+		return false
+	}
+	return TimeRange{Start: ft, End: to}.Contains(now)
+}
+
+// durationTolerance is the difference allowed between Dur and To-Ft.
+const durationTolerance = time.Minute
+
+// DurationConsistent reports whether Dur in seconds matches To minus Ft
+// within durationTolerance.
+// It returns false if any of them is invalid.
+func (p Prog) DurationConsistent() bool {
+	dur, err := strconv.Atoi(p.Dur)
+	if err != nil {
+		return false
+	}
+	ft, err := util.ParseRadikoTime(p.Ft)
+	if err != nil {
+		return false
+	}
+	to, err := util.ParseRadikoTime(p.To)
+	if err != nil {
+		return false
+	}
+	return absDuration(to.Sub(ft)-time.Duration(dur)*time.Second) <= durationTolerance
+}
+
+// Image returns the image url which has the given key.
+func (p Prog) Image(key string) (string, bool) {
+	u, ok := p.Images[key]
+	return u, ok
+}
+
+// Performers returns the performers in Pfm,
+// split at the commas, the Japanese commas or the slashes.
+// It always returns a non-nil slice.
+func (p Prog) Performers() []string {
+	fields := strings.FieldsFunc(p.Pfm, func(r rune) bool {
+		switch r {
+		case ',', '、', '，', '/', '／':
+			return true
+		}
+		return false
+	})
+
+	performers := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			performers = append(performers, f)
+		}
+	}
+	return performers
+}
+
+// Hash returns a short hash that identifies the program.
+// It is computed from MasterID, Ft and Title only,
+// so it is stable across the stations and the other fields' changes.
+func (p Prog) Hash() string {
+	h := sha256.New()
+	for _, s := range []string{p.MasterID, p.Ft, p.Title} {
+		io.WriteString(h, s)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Images is a map of the named image url in the program's metas.
+type Images map[string]string
+
+var imageExts = []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
+
+// UnmarshalXML decodes the metas element and keeps only the image metas.
+func (i *Images) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var metas struct {
+		Meta []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"meta"`
+	}
+	if err := d.DecodeElement(&metas, &start); err != nil {
+		return err
+	}
+
+	for _, m := range metas.Meta {
+		if m.Name == "" || !isImageURL(m.Value) {
+			continue
+		}
+		if *i == nil {
+			*i = make(Images)
+		}
+		(*i)[m.Name] = m.Value
+	}
+	return nil
+}
+
+func isImageURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	for _, e := range imageExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Client) GetRadioStations(ctx context.Context) (RadioStations, error) {
-	apiEndpoint := path.Join(apiV3, "station/list", fmt.Sprintf("%s.xml", c.AreaID()))
+	return c.GetRadioStationsForArea(ctx, c.contextAreaID(ctx))
+}
+
+// GetRadioStationsForArea is like GetRadioStations,
+// but returns the stations in the given area instead of the Client's area.
+func (c *Client) GetRadioStationsForArea(ctx context.Context, areaID string) (RadioStations, error) {
+	if areaID == "" {
+		return nil, errors.New("AreaID is empty")
+	}
 
-	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
+	c.mu.Lock()
+	stations, ok := c.stationDirectory[areaID]
+	c.mu.Unlock()
+	if ok {
+		return stations.clone(), nil
+	}
+	return c.getRadioStations(ctx, areaID)
+}
+
+// GetStationByID returns the station in the Client's area which has the stationID.
+// The stationID is case-sensitive.
+// If the station is not found, it returns ErrStationNotFound.
+func (c *Client) GetStationByID(ctx context.Context, stationID string) (*RadioStation, error) {
+	if stationID == "" {
+		return nil, errors.New("StationID is empty")
+	}
+
+	stations, err := c.GetRadioStations(ctx)
 	if err != nil {
 		return nil, err
 	}
+	for _, s := range stations {
+		if s.ID == stationID {
+			matched := s
+			return &matched, nil
+		}
+	}
+	return nil, ErrStationNotFound
+}
 
-	resp, err := c.Do(req)
+// GetStationStreamInfo returns the urls to create the playlists
+// of the station's streams, listed in the station list.
+// If the station is not found, it returns ErrStationNotFound.
+func (c *Client) GetStationStreamInfo(ctx context.Context, stationID string) ([]StreamURL, error) {
+	station, err := c.GetStationByID(ctx, stationID)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return station.StreamURLs, nil
+}
+
+func (c *Client) getRadioStations(ctx context.Context, areaID string) (RadioStations, error) {
+	apiEndpoint := path.Join(apiV3, "station/list", fmt.Sprintf("%s.xml", areaID))
+	if v, ok := c.cache.get(apiEndpoint); ok {
+		return v.(RadioStations).clone(), nil
+	}
 
 	var d radioStationsData
-	if err = decodeRadioStationsData(resp.Body, &d); err != nil {
+	err := c.getXML(ctx, apiEndpoint, &Params{}, func(r io.Reader) error {
+		d = radioStationsData{}
+		return decodeRadioStationsData(r, &d)
+	})
+	if err != nil {
 		return nil, err
 	}
-	return d.radioStations(), nil
+
+	stations := d.radioStations()
+	c.cache.set(apiEndpoint, stations.clone(), false)
+	return stations, nil
 }
 
 func (c *Client) GetProgramsByStation(ctx context.Context, stationId string, date time.Time) ([]Prog, error) {
-	apiEndpoint := path.Join(apiV3, "program/station/date", util.ProgramsDate(date), fmt.Sprintf("%s.xml", stationId))
-	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
-	if err != nil {
+	if err := ValidateStationID(stationId); err != nil {
 		return nil, err
 	}
 
-	resp, err := c.Do(req)
+	apiEndpoint := path.Join(apiV3, "program/station/date", util.ProgramsDate(date), fmt.Sprintf("%s.xml", stationId))
+
+	var d stationsData
+	err := c.getXML(ctx, apiEndpoint, &Params{}, func(r io.Reader) error {
+		d = stationsData{}
+		return c.decodeStationsData(r, &d)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	if !d.stations().contains(stationId) {
+		return nil, ErrStationNotFound
+	}
+	return broadcastDayPrograms(d.programs(stationId), date), nil
+}
 
-	var d stationsData
-	if err = decodeStationsData(resp.Body, &d); err != nil {
-		return nil, err
+// broadcastDayPrograms returns the programs which belong to
+// the broadcast day of date, deduplicated by Ft and sorted by the start time.
+// radiko may include the programs of the adjacent days around 05:00,
+// and may list a program crossing midnight twice.
+// The programs whose Ft is invalid are kept.
+func broadcastDayPrograms(progs []Prog, date time.Time) []Prog {
+	day := util.ProgramsDate(date)
+	seen := make(map[string]bool, len(progs))
+	filtered := make([]Prog, 0, len(progs))
+	for _, p := range progs {
+		if bd, err := p.BroadcastDate(); err == nil && util.Date(bd) != day {
+			continue
+		}
+		if seen[p.Ft] {
+			continue
+		}
+		seen[p.Ft] = true
+		filtered = append(filtered, p)
 	}
-	return d.programs(), nil
+	sort.Stable(byStartTime(filtered))
+	return filtered
 }
 
-func (c *Client) FindProgramByStation(ctx context.Context, stationId string, date time.Time) (*Prog, error) {
-	progs, err := c.GetProgramsByStation(ctx, stationId, date)
+// GetProgramsByDateRange returns the station's programs of the broadcast days
+// from the day of from to the day of to, sorted by the start time.
+// The days are fetched concurrently, and the programs which appear
+// in two days at the day boundary are included only once.
+func (c *Client) GetProgramsByDateRange(ctx context.Context, stationID string, from, to time.Time) ([]Prog, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, errors.New("from must be before to")
+	}
+
+	var dates []time.Time
+	last := util.ProgramsDate(to)
+	for d := util.BroadcastDate(from); util.ProgramsDate(d) <= last; d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d)
+	}
+
+	days := make([][]Prog, len(dates))
+	err := c.parallel(ctx, len(dates), func(ctx context.Context, i int) error {
+		progs, err := c.GetProgramsByStation(ctx, stationID, dates[i])
+		days[i] = progs
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	target, err := strconv.Atoi(util.Datetime(date))
+	seen := make(map[string]bool)
+	var progs []Prog
+	for _, day := range days {
+		for _, p := range day {
+			key := p.Ft
+			if ft, err := p.StartTime(); err == nil {
+				key = ft.Format(time.RFC3339)
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			progs = append(progs, p)
+		}
+	}
+	return Station{Progs: Progs{Progs: progs}}.sortedPrograms(), nil
+}
+
+func (c *Client) FindProgramByStation(ctx context.Context, stationId string, date time.Time) (*Prog, error) {
+	progs, err := c.GetProgramsByStation(ctx, stationId, date)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, prog := range progs {
-		from, err := strconv.Atoi(prog.Ft)
+		from, err := prog.StartTime()
 		if err != nil {
 			return nil, err
 		}
-		to, err := strconv.Atoi(prog.To)
+		to, err := prog.EndTime()
 		if err != nil {
 			return nil, err
 		}
-		if from <= target && to > target {
-			return &prog, nil
+		if !date.Before(from) && to.After(date) {
+			matched := prog
+			return &matched, nil
 		}
 	}
-	return nil, errors.New("CAN'T FIND THE PROGRAM")
+	return nil, ErrProgramNotFound
 }
 
 // GetStations returns the program's meta-info.
 func (c *Client) GetStations(ctx context.Context, date time.Time) (Stations, error) {
-	apiEndpoint := path.Join(apiV3,
-		"program/date", util.ProgramsDate(date),
-		fmt.Sprintf("%s.xml", c.AreaID()))
+	return c.GetStationsForArea(ctx, c.contextAreaID(ctx), date)
+}
+
+// GetStationsForArea is like GetStations,
+// but returns the programs in the given area instead of the Client's area.
+func (c *Client) GetStationsForArea(ctx context.Context, areaID string, date time.Time) (Stations, error) {
+	if areaID == "" {
+		return nil, errors.New("AreaID is empty")
+	}
 
-	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
+	d, err := c.getStationsData(ctx, areaID, date)
 	if err != nil {
 		return nil, err
 	}
+	return d.stations(), nil
+}
 
-	resp, err := c.Do(req)
+// GetAllProgramsByDate returns the full day schedules of all the stations
+// in the Client's area for the date.
+// The stations missing their schedules in the area's response are fetched
+// station by station concurrently. If one of them fails,
+// the outstanding requests are canceled.
+func (c *Client) GetAllProgramsByDate(ctx context.Context, date time.Time) (Stations, error) {
+	stations, err := c.GetStations(ctx, date)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	var missing []int
+	for i, s := range stations {
+		if len(s.Progs.Progs) == 0 {
+			missing = append(missing, i)
+		}
+	}
+
+	err = c.parallel(ctx, len(missing), func(ctx context.Context, i int) error {
+		s := &stations[missing[i]]
+		progs, err := c.GetProgramsByStation(ctx, s.ID, date)
+		if errors.Is(err, ErrStationNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		s.Progs.Progs = progs
+		if s.Progs.Date == "" {
+			s.Progs.Date = util.ProgramsDate(date)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+// DataCoverage returns each station ID mapped to its number of programs
+// for the date in the area. Zero means the schedule is missing.
+// This API wraps GetStations.
+func (c *Client) DataCoverage(ctx context.Context, areaID string, date time.Time) (map[string]int, error) {
+	list, err := c.DataCoverageOrdered(ctx, areaID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := make(map[string]int, len(list))
+	for _, sc := range list {
+		coverage[sc.StationID] += sc.Programs
+	}
+	return coverage, nil
+}
+
+// StationCoverage is the number of programs of a station.
+type StationCoverage struct {
+	StationID string
+	Programs  int
+}
+
+// DataCoverageOrdered is like DataCoverage, but returns the result
+// in the order of the stations in the response.
+func (c *Client) DataCoverageOrdered(ctx context.Context, areaID string, date time.Time) ([]StationCoverage, error) {
+	if areaID == "" {
+		return nil, errors.New("AreaID is empty")
+	}
+
+	stations, err := c.GetStationsForArea(ctx, areaID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]StationCoverage, 0, len(stations))
+	for _, s := range stations {
+		list = append(list, StationCoverage{
+			StationID: s.ID,
+			Programs:  len(s.Progs.Progs),
+		})
+	}
+	return list, nil
+}
+
+// GetStationsWithArea is like GetStations,
+// but also returns the area which the stations data is for.
+func (c *Client) GetStationsWithArea(ctx context.Context, date time.Time) (Stations, Area, error) {
+	d, err := c.getStationsData(ctx, c.contextAreaID(ctx), date)
+	if err != nil {
+		return nil, Area{}, err
+	}
+	return d.stations(), d.area(), nil
+}
+
+func (c *Client) getStationsData(ctx context.Context, areaID string, date time.Time) (*stationsData, error) {
+	apiEndpoint := programsDateEndpoint(areaID, date)
+	if v, ok := c.cache.get(apiEndpoint); ok {
+		return v.(*stationsData).clone(), nil
+	}
 
 	var d stationsData
-	if err = decodeStationsData(resp.Body, &d); err != nil {
+	err := c.getXML(ctx, apiEndpoint, &Params{}, func(r io.Reader) error {
+		d = stationsData{}
+		return c.decodeStationsData(r, &d)
+	})
+	if err != nil {
 		return nil, err
 	}
-	return d.stations(), nil
+
+	c.cache.set(apiEndpoint, d.clone(), isPastProgramsDate(date))
+	return &d, nil
+}
+
+// GetStationsRaw is like GetStations, but also returns the raw XML
+// of the response to read the elements which Station does not have.
+// The response is not cached.
+func (c *Client) GetStationsRaw(ctx context.Context, date time.Time) (Stations, []byte, error) {
+	var (
+		d   stationsData
+		raw []byte
+	)
+	err := c.getXML(ctx, programsDateEndpoint(c.contextAreaID(ctx), date), &Params{}, func(r io.Reader) error {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		raw = b
+		d = stationsData{}
+		if err = unmarshalXML(b, &d); err != nil {
+			return err
+		}
+		c.normalizeStations(d.stations())
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return d.stations(), raw, nil
+}
+
+// programsDateEndpoint returns the endpoint of the area's programs of the date.
+func programsDateEndpoint(areaID string, date time.Time) string {
+	return path.Join(apiV3,
+		"program/date", util.ProgramsDate(date),
+		fmt.Sprintf("%s.xml", areaID))
 }
 
 // GetNowPrograms returns the program's meta-info which are currently on the air.
 func (c *Client) GetNowPrograms(ctx context.Context) (Stations, error) {
+	return c.GetNowProgramsForArea(ctx, c.contextAreaID(ctx))
+}
+
+// GetNowProgramsForArea is like GetNowPrograms,
+// but returns the programs in the given area instead of the Client's area.
+// If radiko returns no station for the area other than the Client's area,
+// it returns ErrAreaRestricted.
+func (c *Client) GetNowProgramsForArea(ctx context.Context, areaID string) (Stations, error) {
+	if areaID == "" {
+		return nil, errors.New("AreaID is empty")
+	}
+
 	apiEndpoint := apiPath(apiV2, "program/now")
 
-	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{
+	var d stationsData
+	err := c.getXML(ctx, apiEndpoint, &Params{
 		query: map[string]string{
-			"area_id": c.AreaID(),
+			"area_id": areaID,
 		},
+	}, func(r io.Reader) error {
+		d = stationsData{}
+		return c.decodeStationsData(r, &d)
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.Do(req)
+	stations := d.stations()
+	if len(stations) == 0 && areaID != c.AreaID() {
+		return nil, ErrAreaRestricted
+	}
+	return stations, nil
+}
+
+// GetNowOnAir returns the program of the station which is currently on the air
+// in the Client's area.
+// radiko has no API for the now program of a single station,
+// so the now programs of the area are fetched and filtered.
+// If the station is not found, it returns ErrProgramNotFound.
+func (c *Client) GetNowOnAir(ctx context.Context, stationID string) (*Prog, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return nil, err
+	}
+
+	stations, err := c.GetNowPrograms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return currentProgram(stations, stationID, time.Now())
+}
+
+// NowPlayingMulti returns the program of each station
+// which is currently on the air, keyed by the station id.
+// The schedules of the stations are fetched concurrently.
+// If some of the stations fail, the others are still returned
+// with StationErrors which has the errors of the failed ones.
+func (c *Client) NowPlayingMulti(ctx context.Context, stationIDs []string) (map[string]*Prog, error) {
+	now := time.Now()
+
+	var (
+		mu     sync.Mutex
+		progs  = make(map[string]*Prog, len(stationIDs))
+		errMap = make(StationErrors)
+	)
+	err := c.parallel(ctx, len(stationIDs), func(ctx context.Context, i int) error {
+		id := stationIDs[i]
+		var prog *Prog
+		day, err := c.GetProgramsByStation(ctx, id, now)
+		if err == nil {
+			prog, err = Station{ID: id, Progs: Progs{Progs: day}}.CurrentProgram(now)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errMap[id] = err
+			return nil
+		}
+		progs[id] = prog
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	if len(errMap) > 0 {
+		return progs, errMap
+	}
+	return progs, nil
+}
 
-	var d stationsData
-	if err = decodeStationsData(resp.Body, &d); err != nil {
+// GetProgramByID returns the program which has the programID
+// in the Client's area.
+// radiko has no API for a single program, so the programs of the days
+// from timeshiftDays ago to timeshiftDays later are fetched concurrently
+// and searched.
+// If the program is not found, it returns ErrProgramNotFound.
+func (c *Client) GetProgramByID(ctx context.Context, programID string) (*Prog, error) {
+	if programID == "" {
+		return nil, errors.New("ProgramID is empty")
+	}
+
+	now := time.Now()
+	days := make([]Stations, 2*timeshiftDays+1)
+	err := c.parallel(ctx, len(days), func(ctx context.Context, i int) error {
+		stations, err := c.GetStations(ctx, now.AddDate(0, 0, i-timeshiftDays))
+		days[i] = stations
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
-	return d.stations(), nil
+
+	for _, stations := range days {
+		for _, s := range stations {
+			for _, p := range s.Progs.Progs {
+				if p.ID == programID {
+					matched := p
+					return &matched, nil
+				}
+			}
+		}
+	}
+	return nil, ErrProgramNotFound
 }
 
 // GetProgramByStartTime returns a specified program.
 // This API wraps GetStations.
 func (c *Client) GetProgramByStartTime(ctx context.Context, stationID string, start time.Time) (*Prog, error) {
-	if stationID == "" {
-		return nil, errors.New("StationID is empty")
+	prog, _, err := c.GetProgramNearStartTime(ctx, stationID, start)
+	return prog, err
+}
+
+// GetProgramByDatetime returns the station's program in the Client's area
+// which is on the air at t, that is, whose [Ft, To) contains t.
+// If no program contains t, it returns ErrProgramNotFound.
+func (c *Client) GetProgramByDatetime(ctx context.Context, stationID string, t time.Time) (*Prog, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return nil, err
 	}
 
-	stations, err := c.GetStations(ctx, start)
+	stations, err := c.GetStations(ctx, t)
 	if err != nil {
 		return nil, err
 	}
+	station, ok := stations.FindByID(stationID)
+	if !ok {
+		return nil, ErrProgramNotFound
+	}
+	return station.CurrentProgram(t)
+}
+
+// GetProgramNearStartTime is like GetProgramByStartTime,
+// but also returns the offset of the program's start time from start.
+// The offset is non-zero only if start is clamped by the timeshift tolerance,
+// and negative if the program starts before start.
+func (c *Client) GetProgramNearStartTime(ctx context.Context, stationID string, start time.Time) (*Prog, time.Duration, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return nil, 0, err
+	}
+
+	stations, err := c.GetStations(ctx, start)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	ft := util.Datetime(start)
-	var prog *Prog
+	var (
+		prog  *Prog
+		delta time.Duration
+	)
 	for _, s := range stations {
 		if s.ID == stationID {
 			for _, p := range s.Progs.Progs {
 				if p.Ft == ft {
-					prog = &p
+					matched := p
+					prog = &matched
 					break
 				}
 			}
 		}
 	}
+	if prog == nil && c.timeshiftTolerance > 0 {
+		prog, delta = nearestProgram(stations, stationID, start, c.timeshiftTolerance)
+		if prog != nil {
+			c.logf("radiko: start time %s is clamped to %s", ft, prog.Ft)
+		}
+	}
 	if prog == nil {
-		return nil, ErrProgramNotFound
+		return nil, 0, ErrProgramNotFound
 	}
-	return prog, nil
+	return prog, delta, nil
+}
+
+// nearestProgram returns the program whose start time is the nearest to start
+// within the tolerance, and the offset of its start time from start.
+func nearestProgram(stations Stations, stationID string, start time.Time, tolerance time.Duration) (*Prog, time.Duration) {
+	var (
+		prog  *Prog
+		delta time.Duration
+	)
+	for _, s := range stations {
+		if s.ID != stationID {
+			continue
+		}
+		for i := range s.Progs.Progs {
+			p := &s.Progs.Progs[i]
+			ft, err := util.ParseRadikoTime(p.Ft)
+			if err != nil {
+				continue
+			}
+			d := ft.Sub(start)
+			if absDuration(d) <= tolerance && (prog == nil || absDuration(d) < absDuration(delta)) {
+				prog, delta = p, d
+			}
+		}
+	}
+	return prog, delta
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
 }
 
 // GetWeeklyPrograms returns the weekly programs.
+// If the station does not exist, it returns ErrStationNotFound.
 func (c *Client) GetWeeklyPrograms(ctx context.Context, stationID string) (Stations, error) {
-	apiEndpoint := path.Join(apiV3,
-		"program/station/weekly",
-		fmt.Sprintf("%s.xml", stationID))
+	apiEndpoint, err := weeklyProgramsEndpoint(stationID)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := c.cache.get(apiEndpoint); ok {
+		return v.(Stations).clone(), nil
+	}
 
-	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
+	var d stationsData
+	err = c.getWeeklyPrograms(ctx, apiEndpoint, func(r io.Reader) error {
+		d = stationsData{}
+		return c.decodeStationsData(r, &d)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.Do(req)
+	stations := d.stations()
+	if !stations.contains(stationID) {
+		return nil, ErrStationNotFound
+	}
+	c.cache.set(apiEndpoint, stations.clone(), false)
+	return stations, nil
+}
+
+// GetWeeklyProgramsMulti returns the weekly programs of the stations
+// in the order of stationIDs, fetched concurrently.
+// If any station fails, it returns the error.
+func (c *Client) GetWeeklyProgramsMulti(ctx context.Context, stationIDs []string) (Stations, error) {
+	stations, err := c.getWeeklyProgramsMulti(ctx, stationIDs)
+	if errMap, ok := err.(StationErrors); ok {
+		return nil, errMap[firstFailed(stationIDs, errMap)]
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return stations, nil
+}
 
-	var d stationsData
-	if err = decodeStationsData(resp.Body, &d); err != nil {
+// GetWeeklyProgramsMultiPartial is like GetWeeklyProgramsMulti,
+// but skips the stations which fail and continues.
+// If some stations fail, it returns the others with StationErrors.
+func (c *Client) GetWeeklyProgramsMultiPartial(ctx context.Context, stationIDs []string) (Stations, error) {
+	return c.getWeeklyProgramsMulti(ctx, stationIDs)
+}
+
+func (c *Client) getWeeklyProgramsMulti(ctx context.Context, stationIDs []string) (Stations, error) {
+	var (
+		mu      sync.Mutex
+		results = make([]*Station, len(stationIDs))
+		errMap  = make(StationErrors)
+	)
+	err := c.parallel(ctx, len(stationIDs), func(ctx context.Context, i int) error {
+		id := stationIDs[i]
+		weekly, err := c.GetWeeklyPrograms(ctx, id)
+		if err != nil {
+			mu.Lock()
+			errMap[id] = err
+			mu.Unlock()
+			return nil
+		}
+		// GetWeeklyPrograms has checked that the station exists.
+		results[i], _ = weekly.FindByID(id)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-	return d.stations(), nil
+
+	stations := make(Stations, 0, len(stationIDs))
+	for _, s := range results {
+		if s != nil {
+			stations = append(stations, *s)
+		}
+	}
+	if len(errMap) > 0 {
+		return stations, errMap
+	}
+	return stations, nil
+}
+
+// firstFailed returns the first id in ids which has an error in errMap.
+func firstFailed(ids []string, errMap StationErrors) string {
+	for _, id := range ids {
+		if _, ok := errMap[id]; ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// GetProgramsForDay returns the station's programs of the broadcast day
+// which day belongs to, extracted from the weekly programs.
+// If the week has no program on the day, it returns an empty slice.
+func (c *Client) GetProgramsForDay(ctx context.Context, stationID string, day time.Time) ([]Prog, error) {
+	stations, err := c.GetWeeklyPrograms(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+	station, ok := stations.FindByID(stationID)
+	if !ok {
+		return nil, ErrStationNotFound
+	}
+
+	progs := station.ProgramsByDay()[util.ProgramsDate(day)]
+	if progs == nil {
+		return []Prog{}, nil
+	}
+	return progs, nil
+}
+
+// GetWeeklyProgramsStream calls onStation with each station
+// as soon as it is decoded, instead of after the full parse.
+// If onStation returns an error, decoding stops and the error is returned.
+// If no station is decoded, it returns ErrStationNotFound.
+func (c *Client) GetWeeklyProgramsStream(ctx context.Context, stationID string, onStation func(Station) error) error {
+	if onStation == nil {
+		return errors.New("onStation is nil")
+	}
+
+	apiEndpoint, err := weeklyProgramsEndpoint(stationID)
+	if err != nil {
+		return err
+	}
+	if v, ok := c.cache.get(apiEndpoint); ok {
+		for _, station := range v.(Stations) {
+			if err = onStation(station.clone()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// emitted is the number of the stations passed to onStation,
+	// which are skipped if the truncated response is retried.
+	var emitted int
+	return c.getWeeklyPrograms(ctx, apiEndpoint, func(r io.Reader) error {
+		var decoded int
+		decoder := newXMLDecoder(r)
+		for {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			start, ok := token.(xml.StartElement)
+			if !ok || start.Name.Local != "station" {
+				continue
+			}
+
+			var station Station
+			if err = decoder.DecodeElement(&station, &start); err != nil {
+				return err
+			}
+			decoded++
+			if decoded <= emitted {
+				continue
+			}
+			c.normalizeStation(&station)
+			emitted++
+			if err = onStation(station); err != nil {
+				return err
+			}
+		}
+
+		if emitted == 0 {
+			return ErrStationNotFound
+		}
+		return nil
+	})
+}
+
+// weeklyProgramsEndpoint returns the endpoint of the station's weekly programs.
+func weeklyProgramsEndpoint(stationID string) (string, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return "", err
+	}
+	return path.Join(apiV3,
+		"program/station/weekly",
+		fmt.Sprintf("%s.xml", stationID)), nil
+}
+
+// getWeeklyPrograms fetches the weekly programs by getXML.
+// If radiko responds with 404, it returns ErrStationNotFound.
+func (c *Client) getWeeklyPrograms(ctx context.Context, apiEndpoint string, decode func(io.Reader) error) error {
+	err := c.getXML(ctx, apiEndpoint, &Params{}, decode)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return ErrStationNotFound
+	}
+	return err
+}
+
+// AiringCalendar returns the programs which have the given title
+// mapped to the weekday of the broadcast day they air on.
+// If the title airs more than once a day, the earliest one is used.
+// This API wraps GetWeeklyPrograms.
+func (c *Client) AiringCalendar(ctx context.Context, stationID, title string) (map[time.Weekday]*Prog, error) {
+	stations, err := c.GetWeeklyPrograms(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := make(map[time.Weekday]*Prog)
+	for _, s := range stations {
+		if s.ID != stationID {
+			continue
+		}
+		for i := range s.Progs.Progs {
+			p := &s.Progs.Progs[i]
+			if p.Title != title {
+				continue
+			}
+			ft, err := util.ParseRadikoTime(p.Ft)
+			if err != nil {
+				return nil, err
+			}
+			weekday := util.BroadcastDate(ft).Weekday()
+			if _, ok := calendar[weekday]; !ok {
+				calendar[weekday] = p
+			}
+		}
+	}
+	return calendar, nil
+}
+
+// FindWeeklyAirings returns all the airings of the title on the station
+// in the weekly programs, as Station.AiringsOfTitle does.
+// If the title does not air this week, it returns an empty slice.
+func (c *Client) FindWeeklyAirings(ctx context.Context, stationID, title string) ([]Prog, error) {
+	stations, err := c.GetWeeklyPrograms(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+	station, ok := stations.FindByID(stationID)
+	if !ok {
+		return nil, ErrStationNotFound
+	}
+	return station.AiringsOfTitle(title), nil
+}
+
+// contains reports whether stations include the given stationID.
+func (s Stations) contains(stationID string) bool {
+	_, ok := s.FindByID(stationID)
+	return ok
+}
+
+// FindByID returns the station which has the id.
+// If there is no such station, it returns nil and false.
+func (s Stations) FindByID(id string) (*Station, bool) {
+	for _, station := range s {
+		if station.ID == id {
+			matched := station
+			return &matched, true
+		}
+	}
+	return nil, false
+}
+
+// FilterByIDs returns the stations which have one of the ids,
+// in the order of s.
+func (s Stations) FilterByIDs(ids ...string) Stations {
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+
+	var filtered Stations
+	for _, station := range s {
+		if want[station.ID] {
+			filtered = append(filtered, station)
+		}
+	}
+	return filtered
+}
+
+// IDs returns the ids of the stations in order.
+func (s Stations) IDs() []string {
+	ids := make([]string, len(s))
+	for i, station := range s {
+		ids[i] = station.ID
+	}
+	return ids
+}
+
+// SortByName sorts the stations by the name in place.
+// The stations which have the same name keep their order.
+func (s Stations) SortByName() {
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Name < s[j].Name
+	})
 }
 
 type radioStationsData struct {
@@ -244,7 +1383,7 @@ func decodeRadioStationsData(input io.Reader, stations *radioStationsData) error
 	if err != nil {
 		return err
 	}
-	if err = xml.Unmarshal(b, stations); err != nil {
+	if err = unmarshalXML(b, stations); err != nil {
 		return err
 	}
 	return nil
@@ -255,17 +1394,138 @@ type stationsData struct {
 	XMLName     xml.Name `xml:"radiko"`
 	XMLStations struct {
 		XMLName  xml.Name `xml:"stations"`
+		AreaID   string   `xml:"area_id,attr"`
+		AreaName string   `xml:"area_name,attr"`
 		Stations Stations `xml:"station"`
 	} `xml:"stations"`
 }
 
+// area returns the area which the stations data is for.
+func (d *stationsData) area() Area {
+	return Area{
+		ID:   d.XMLStations.AreaID,
+		Name: d.XMLStations.AreaName,
+	}
+}
+
 // stations returns Stations which is a response struct for client's users.
 func (d *stationsData) stations() Stations {
 	return d.XMLStations.Stations
 }
 
-func (d *stationsData) programs() []Prog {
-	return d.XMLStations.Stations[0].Progs.Progs
+// programs returns the programs of the station which has the stationID.
+// radiko may return other stations with the requested one,
+// so the station is selected by the id.
+// It returns an empty slice if there is no such station.
+func (d *stationsData) programs(stationID string) []Prog {
+	station, ok := d.stations().FindByID(stationID)
+	if !ok {
+		return []Prog{}
+	}
+	return station.Progs.Progs
+}
+
+// utf8BOM is the byte order mark which radiko sometimes prepends to the XML.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// newXMLDecoder returns a xml.Decoder which skips the UTF-8 BOM
+// and decodes the charsets other than UTF-8 declared in the XML.
+func newXMLDecoder(r io.Reader) *xml.Decoder {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(b, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+
+	decoder := xml.NewDecoder(br)
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder
+}
+
+// unmarshalXML is like xml.Unmarshal, but decodes b by newXMLDecoder.
+func unmarshalXML(b []byte, v interface{}) error {
+	return newXMLDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+// DecodeStations decodes the XML of the programs of the stations,
+// like a saved response of GetStations.
+func DecodeStations(r io.Reader) (Stations, error) {
+	var d stationsData
+	if err := decodeStationsData(r, &d); err != nil {
+		return nil, err
+	}
+	return d.stations(), nil
+}
+
+// DecodePrograms is like DecodeStations,
+// but returns the programs of all the stations in order.
+func DecodePrograms(r io.Reader) ([]Prog, error) {
+	stations, err := DecodeStations(r)
+	if err != nil {
+		return nil, err
+	}
+
+	progs := []Prog{}
+	for _, s := range stations {
+		progs = append(progs, s.programs()...)
+	}
+	return progs, nil
+}
+
+// DecodeRadioStations decodes the XML of the station list,
+// like a saved response of GetRadioStations.
+func DecodeRadioStations(r io.Reader) (RadioStations, error) {
+	var d radioStationsData
+	if err := decodeRadioStationsData(r, &d); err != nil {
+		return nil, err
+	}
+	return d.radioStations(), nil
+}
+
+// decodeStationsData is like the function decodeStationsData,
+// but normalizes the text of the programs
+// if WithTextNormalization or WithNFKCNormalization is enabled.
+func (c *Client) decodeStationsData(input io.Reader, stations *stationsData) error {
+	if err := decodeStationsData(input, stations); err != nil {
+		return err
+	}
+	c.normalizeStations(stations.stations())
+	return nil
+}
+
+// normalizeStations normalizes the text of the programs in place
+// if WithTextNormalization or WithNFKCNormalization is enabled.
+func (c *Client) normalizeStations(stations Stations) {
+	for i := range stations {
+		c.normalizeStation(&stations[i])
+	}
+}
+
+// normalizeStation is like normalizeStations for a station.
+// Both of the progs and the scd elements are normalized.
+func (c *Client) normalizeStation(s *Station) {
+	if !c.normalizeText && !c.normalizeNFKC {
+		return
+	}
+	for _, progs := range [][]Prog{s.Progs.Progs, s.Scd.Progs.Progs} {
+		for i := range progs {
+			progs[i].normalizeText(c.normalizeText, c.normalizeNFKC)
+		}
+	}
+}
+
+// normalizeText unescapes the HTML entities if unescape is true,
+// and applies NFKC if nfkc is true to the text fields of the program.
+// NFKC folds the full-width alphanumerics to the half-width ones
+// and the half-width katakana to the full-width ones.
+func (p *Prog) normalizeText(unescape, nfkc bool) {
+	for _, s := range []*string{&p.Title, &p.SubTitle, &p.Desc, &p.Pfm, &p.Info} {
+		if unescape {
+			*s = html.UnescapeString(*s)
+		}
+		if nfkc {
+			*s = norm.NFKC.String(*s)
+		}
+	}
 }
 
 // decodeStationsData parses the XML-encoded data and stores the result.
@@ -275,7 +1535,7 @@ func decodeStationsData(input io.Reader, stations *stationsData) error {
 		return err
 	}
 
-	if err = xml.Unmarshal(b, stations); err != nil {
+	if err = unmarshalXML(b, stations); err != nil {
 		return err
 	}
 	return nil
@@ -2,14 +2,25 @@ package radiko
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/chikulla/go-radiko/internal/util"
 )
 
+// programsFixtureDate is in the broadcast day of the programs in the fixtures.
+var programsFixtureDate = time.Date(2016, 11, 12, 12, 0, 0, 0, util.Location())
+
 func client() (*Client, error) {
 	c, err := New("")
 	c.SetAreaID(areaIDTokyo)
@@ -69,6 +80,61 @@ func TestGetNowPrograms(t *testing.T) {
 	}
 }
 
+func TestGetNowOnAir(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, nowProgramsFormat, "20161112200000", "20161112210000", "first")
+	}))
+	defer closer()
+
+	prog, err := c.GetNowOnAir(context.Background(), "TBS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "first"; expected != prog.Title {
+		t.Errorf("expected %s, but %s", expected, prog.Title)
+	}
+
+	if _, err := c.GetNowOnAir(context.Background(), "LFR"); err != ErrProgramNotFound {
+		t.Errorf("expected %s, but %v", ErrProgramNotFound, err)
+	}
+	if _, err := c.GetNowOnAir(context.Background(), ""); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestNowPlayingMulti(t *testing.T) {
+	now := time.Now()
+	ft, to := util.Datetime(now.Add(-time.Hour)), util.Datetime(now.Add(time.Hour))
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case "TBS.xml", "LFR.xml":
+			id := strings.TrimSuffix(path.Base(r.URL.Path), ".xml")
+			fmt.Fprintf(w, `<radiko><stations><station id="%s"><progs><prog ft="%s" to="%s"><title>%s now</title></prog></progs></station></stations></radiko>`, id, ft, to, id)
+		default:
+			w.Write([]byte(`<radiko><stations></stations></radiko>`))
+		}
+	}))
+	defer closer()
+
+	progs, err := c.NowPlayingMulti(context.Background(), []string{"TBS", "QRR", "LFR"})
+	errs, ok := err.(StationErrors)
+	if !ok {
+		t.Fatalf("expected StationErrors, but %v", err)
+	}
+	if len(errs) != 1 || errs["QRR"] != ErrStationNotFound {
+		t.Errorf("expected QRR: %s, but %v", ErrStationNotFound, errs)
+	}
+
+	if len(progs) != 2 {
+		t.Fatalf("expected 2 programs, but %v", progs)
+	}
+	for _, id := range []string{"TBS", "LFR"} {
+		if expected := id + " now"; progs[id] == nil || progs[id].Title != expected {
+			t.Errorf("expected %s, but %v", expected, progs[id])
+		}
+	}
+}
+
 func TestGetProgramByStartTime(t *testing.T) {
 	if isOutsideJP() {
 		t.Skip("Skipping test in limited mode.")
@@ -150,6 +216,48 @@ func TestGetWeeklyPrograms(t *testing.T) {
 	}
 }
 
+func TestGetWeeklyProgramsMulti(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/station/weekly/TBS.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "weekly.xml"))
+		case "/v3/program/station/weekly/QRR.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs_two_stations.xml"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+
+	ctx := context.Background()
+	stations, err := c.GetWeeklyProgramsMulti(ctx, []string{"QRR", "TBS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "QRR,TBS", strings.Join(stations.IDs(), ","); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+	if expected, actual := "40001", stations[0].Progs.Progs[0].ID; expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	if _, err = c.GetWeeklyProgramsMulti(ctx, []string{"QRR", "LFR", "TBS"}); err != ErrStationNotFound {
+		t.Errorf("expected %s, but %v", ErrStationNotFound, err)
+	}
+
+	stations, err = c.GetWeeklyProgramsMultiPartial(ctx, []string{"QRR", "LFR", "TBS"})
+	errMap, ok := err.(StationErrors)
+	if !ok {
+		t.Fatalf("expected StationErrors, but %v", err)
+	}
+	if len(errMap) != 1 || errMap["LFR"] != ErrStationNotFound {
+		t.Errorf("unexpected errors: %v", errMap)
+	}
+	if expected, actual := "QRR,TBS", strings.Join(stations.IDs(), ","); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+}
+
 func TestDecodeStationsData(t *testing.T) {
 	file, err := os.Open(filepath.Join(testdataDir, "stations.xml"))
 	if err != nil {
@@ -166,3 +274,1280 @@ func TestDecodeStationsData(t *testing.T) {
 		t.Errorf("expected number of stations %d, but %d.", expected, len(s))
 	}
 }
+
+func TestGetProgramByStartTime_TimeshiftTolerance(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	ft, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := ft.Add(-90 * time.Second)
+
+	_, err = c.GetProgramByStartTime(context.Background(), "TBS", start)
+	if err != ErrProgramNotFound {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.SetTimeshiftTolerance(5 * time.Minute)
+	prog, err := c.GetProgramByStartTime(context.Background(), "TBS", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "20161112220000"; expected != prog.Ft {
+		t.Errorf("expected %s, but %s", expected, prog.Ft)
+	}
+}
+
+func TestGetProgramByDatetime(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	for _, tt := range []struct {
+		datetime string
+		ft       string
+	}{
+		{"20161112220000", "20161112220000"},
+		{"20161112231500", "20161112220000"},
+		{"20161112215959", "20161112200000"},
+	} {
+		d, err := util.ParseRadikoTime(tt.datetime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prog, err := c.GetProgramByDatetime(context.Background(), "TBS", d)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.datetime, err)
+		}
+		if tt.ft != prog.Ft {
+			t.Errorf("%s: expected %s, but %s", tt.datetime, tt.ft, prog.Ft)
+		}
+	}
+
+	d, err := util.ParseRadikoTime("20161112180000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetProgramByDatetime(context.Background(), "TBS", d); err != ErrProgramNotFound {
+		t.Errorf("expected %s, but %v", ErrProgramNotFound, err)
+	}
+	if _, err := c.GetProgramByDatetime(context.Background(), "FMT", d); err != ErrProgramNotFound {
+		t.Errorf("expected %s, but %v", ErrProgramNotFound, err)
+	}
+}
+
+func TestGetNowProgramsForArea(t *testing.T) {
+	var areas []string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		area := r.URL.Query().Get("area_id")
+		areas = append(areas, area)
+		if area == "JP27" {
+			w.Write([]byte(`<radiko><stations></stations></radiko>`))
+			return
+		}
+		http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+	}))
+	defer closer()
+
+	stations, err := c.GetNowProgramsForArea(context.Background(), "JP14")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) == 0 {
+		t.Error("Stations is nil.")
+	}
+	if _, err := c.GetNowPrograms(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "JP14 JP13", strings.Join(areas, " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	if _, err := c.GetNowProgramsForArea(context.Background(), "JP27"); err != ErrAreaRestricted {
+		t.Errorf("expected %s, but %v", ErrAreaRestricted, err)
+	}
+}
+
+func TestProg_ImageURL(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	progs, err := c.GetProgramsByStation(context.Background(), "TBS", programsFixtureDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progs) != 2 {
+		t.Fatalf("expected 2, but %d", len(progs))
+	}
+	if progs[0].ImageURL != "" {
+		t.Errorf("expected empty, but %s", progs[0].ImageURL)
+	}
+	if expected := "https://radiko.jp/res/program/DEFAULT_IMAGE/TBS/utamaru.jpg"; expected != progs[1].ImageURL {
+		t.Errorf("expected %s, but %s", expected, progs[1].ImageURL)
+	}
+}
+
+func TestGetWeeklyPrograms_ErrStationNotFound(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("weekly_invalid.xml"))
+	defer closer()
+
+	_, err := c.GetWeeklyPrograms(context.Background(), "INVALID")
+	if err != ErrStationNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c, closer = newTestClient(t, http.NotFoundHandler())
+	defer closer()
+
+	_, err = c.GetWeeklyPrograms(context.Background(), "INVALID")
+	if err != ErrStationNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGetWeeklyPrograms_StationFound(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	stations, err := c.GetWeeklyPrograms(context.Background(), "TBS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) == 0 {
+		t.Error("Stations is nil.")
+	}
+}
+
+func TestDataCoverage(t *testing.T) {
+	var reqPath string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqPath = r.URL.Path
+		http.ServeFile(w, r, filepath.Join(testdataDir, "programs_missing.xml"))
+	}))
+	defer closer()
+
+	date, err := util.ParseRadikoTime("20161112120000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	coverage, err := c.DataCoverage(context.Background(), "JP27", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/v3/program/date/20161112/JP27.xml"; expected != reqPath {
+		t.Errorf("expected %s, but %s", expected, reqPath)
+	}
+
+	expected := map[string]int{"TBS": 2, "QRR": 0}
+	if len(coverage) != len(expected) {
+		t.Fatalf("expected %v, but %v", expected, coverage)
+	}
+	for id, n := range expected {
+		if actual, ok := coverage[id]; !ok || n != actual {
+			t.Errorf("%s: expected %d, but %d", id, n, actual)
+		}
+	}
+}
+
+func TestProg_Image(t *testing.T) {
+	file, err := os.Open(filepath.Join(testdataDir, "program_images.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var d stationsData
+	if err = decodeStationsData(file, &d); err != nil {
+		t.Fatal(err)
+	}
+	prog := d.programs("TBS")[0]
+
+	expected := map[string]string{
+		"thumbnail": "https://radiko.jp/res/program/DEFAULT_IMAGE/TBS/thumbnail.jpg",
+		"artwork":   "https://radiko.jp/res/program/DEFAULT_IMAGE/TBS/artwork.png",
+	}
+	if len(prog.Images) != len(expected) {
+		t.Errorf("expected %v, but %v", expected, prog.Images)
+	}
+	for key, u := range expected {
+		if actual, ok := prog.Image(key); !ok || u != actual {
+			t.Errorf("%s: expected %s, but %s", key, u, actual)
+		}
+	}
+	if _, ok := prog.Image("twitter"); ok {
+		t.Error("twitter meta should not be an image.")
+	}
+}
+
+func TestGetProgramsForDay(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("weekly.xml"))
+	defer closer()
+
+	for _, tt := range []struct {
+		day      string
+		expected string
+	}{
+		{"20161114120000", "30001 30002"},
+		{"20161119030000", "30006 30007"},
+		{"20161117120000", ""},
+	} {
+		day, err := util.ParseRadikoTime(tt.day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		progs, err := c.GetProgramsForDay(context.Background(), "TBS", day)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []string
+		for _, p := range progs {
+			ids = append(ids, p.ID)
+		}
+		if actual := strings.Join(ids, " "); tt.expected != actual {
+			t.Errorf("%s: expected %s, but %s", tt.day, tt.expected, actual)
+		}
+	}
+}
+
+func TestAiringCalendar(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("weekly.xml"))
+	defer closer()
+
+	calendar, err := c.AiringCalendar(context.Background(), "TBS", "深夜のラジオ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[time.Weekday]string{
+		time.Monday:    "20161114220000",
+		time.Wednesday: "20161116220000",
+		time.Friday:    "20161119010000",
+	}
+	if len(calendar) != len(expected) {
+		t.Errorf("expected %d weekdays, but %d", len(expected), len(calendar))
+	}
+	for weekday, ft := range expected {
+		prog, ok := calendar[weekday]
+		if !ok {
+			t.Errorf("%s: program not found", weekday)
+			continue
+		}
+		if ft != prog.Ft {
+			t.Errorf("%s: expected %s, but %s", weekday, ft, prog.Ft)
+		}
+	}
+}
+
+func TestFindWeeklyAirings(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("weekly.xml"))
+	defer closer()
+
+	airings, err := c.FindWeeklyAirings(context.Background(), "TBS", "深夜のラジオ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fts []string
+	for _, p := range airings {
+		fts = append(fts, p.Ft)
+	}
+	if expected, actual := "20161114220000 20161116220000 20161119010000", strings.Join(fts, " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	airings, err = c.FindWeeklyAirings(context.Background(), "TBS", "not on air")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if airings == nil || len(airings) != 0 {
+		t.Errorf("expected an empty slice, but %v", airings)
+	}
+}
+
+func TestMergeRadioStations(t *testing.T) {
+	tokyo := RadioStations{
+		{ID: "TBS", Name: "TBSラジオ"},
+		{ID: "QRR", Name: "文化放送"},
+		{ID: "JOAK", Name: "NHKラジオ第1"},
+	}
+	osaka := RadioStations{
+		{ID: "ABC", Name: "ABCラジオ"},
+		{ID: "JOAK", Name: "NHKラジオ第1"},
+		{ID: "MBS", Name: "MBSラジオ"},
+	}
+
+	merged := MergeRadioStations(tokyo, osaka)
+	expected := []string{"TBS", "QRR", "JOAK", "ABC", "MBS"}
+	if len(merged) != len(expected) {
+		t.Fatalf("expected %d stations, but %d", len(expected), len(merged))
+	}
+	for i, id := range expected {
+		if merged[i].ID != id {
+			t.Errorf("expected %s, but %s", id, merged[i].ID)
+		}
+	}
+}
+
+func TestGetWeeklyProgramsStream(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	var ids []string
+	err := c.GetWeeklyProgramsStream(context.Background(), "TBS", func(s Station) error {
+		ids = append(ids, s.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 2; len(ids) != expected {
+		t.Errorf("expected %d calls, but %d", expected, len(ids))
+	}
+
+	stop := errors.New("stop")
+	var calls int
+	err = c.GetWeeklyProgramsStream(context.Background(), "TBS", func(s Station) error {
+		calls++
+		return stop
+	})
+	if err != stop {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expected := 1; calls != expected {
+		t.Errorf("expected %d calls, but %d", expected, calls)
+	}
+}
+
+func TestGetWeeklyProgramsStream_ErrStationNotFound(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("weekly_invalid.xml"))
+	defer closer()
+
+	err := c.GetWeeklyProgramsStream(context.Background(), "INVALID", func(s Station) error {
+		return nil
+	})
+	if err != ErrStationNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDecodeStations(t *testing.T) {
+	f, err := os.Open(filepath.Join(testdataDir, "programs_two_stations.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stations, err := DecodeStations(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "QRR TBS", strings.Join(stations.IDs(), " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	progs, err := DecodePrograms(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 4; len(progs) != expected {
+		t.Errorf("expected %d programs, but %d", expected, len(progs))
+	}
+
+	if _, err := DecodeStations(strings.NewReader("<radiko>")); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestDecodeRadioStations(t *testing.T) {
+	f, err := os.Open(filepath.Join(testdataDir, "station_list.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stations, err := DecodeRadioStations(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 3; len(stations) != expected {
+		t.Errorf("expected %d stations, but %d", expected, len(stations))
+	}
+}
+
+func TestDecodeRadioStationsData_Charset(t *testing.T) {
+	for _, name := range []string{"station_list_bom.xml", "station_list_sjis.xml"} {
+		f, err := os.Open(filepath.Join(testdataDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d radioStationsData
+		err = decodeRadioStationsData(f, &d)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		stations := d.radioStations()
+		if expected := 3; len(stations) != expected {
+			t.Errorf("%s: expected %d stations, but %d", name, expected, len(stations))
+			continue
+		}
+		if expected := "文化放送"; expected != stations[1].Name {
+			t.Errorf("%s: expected %s, but %s", name, expected, stations[1].Name)
+		}
+	}
+}
+
+func TestRadioStation_AvailableInArea(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("station_list.xml"))
+	defer closer()
+
+	stations, err := c.GetRadioStations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 3; len(stations) != expected {
+		t.Fatalf("expected %d stations, but %d", expected, len(stations))
+	}
+
+	cases := []struct {
+		station  RadioStation
+		areaID   string
+		areafree bool
+		expected bool
+	}{
+		{stations[0], "JP14", true, true},
+		{stations[1], "JP14", true, false},
+		{stations[1], "JP13", true, true},
+		{stations[2], "JP27", false, true},
+	}
+	for _, c := range cases {
+		if c.station.Areafree != c.areafree {
+			t.Errorf("%s: expected areafree %v, but %v", c.station.ID, c.areafree, c.station.Areafree)
+		}
+		if actual := c.station.AvailableInArea(c.areaID); c.expected != actual {
+			t.Errorf("%s in %s: expected %v, but %v", c.station.ID, c.areaID, c.expected, actual)
+		}
+	}
+}
+
+func TestRadioStation_LargestLogo(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("station_list.xml"))
+	defer closer()
+
+	stations, err := c.GetRadioStations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 3; len(stations[0].Logos) != expected {
+		t.Fatalf("expected %d logos, but %d", expected, len(stations[0].Logos))
+	}
+	if expected := (Logo{Width: 124, Height: 40, URL: "https://radiko.jp/v2/static/station/logo/TBS/124x40.png"}); stations[0].Logos[0] != expected {
+		t.Errorf("expected %v, but %v", expected, stations[0].Logos[0])
+	}
+	if expected := "https://radiko.jp/v2/static/station/logo/TBS/448x200.png"; stations[0].LargestLogo() != expected {
+		t.Errorf("expected %s, but %s", expected, stations[0].LargestLogo())
+	}
+
+	// QRR has no logo elements.
+	if len(stations[1].Logos) != 0 || stations[1].LargestLogo() != "" {
+		t.Errorf("expected no logo, but %v", stations[1].Logos)
+	}
+}
+
+func TestGetStationStreamInfo(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("station_list.xml"))
+	defer closer()
+
+	urls, err := c.GetStationStreamInfo(context.Background(), "JORF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []StreamURL{
+		{PlaylistCreateURL: "https://f-radiko.smartstream.ne.jp/JORF/_definst_/simul-stream.stream/playlist.m3u8"},
+		{PlaylistCreateURL: "https://radiko.jp/v2/api/ts/playlist.m3u8", Timefree: true},
+	}
+	if len(urls) != len(expected) {
+		t.Fatalf("expected %v, but %v", expected, urls)
+	}
+	for i := range expected {
+		if expected[i] != urls[i] {
+			t.Errorf("expected %v, but %v", expected[i], urls[i])
+		}
+	}
+
+	// TBS has no url elements.
+	urls, err = c.GetStationStreamInfo(context.Background(), "TBS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 0 {
+		t.Errorf("expected no url, but %v", urls)
+	}
+	if _, err = c.GetStationStreamInfo(context.Background(), "LFR"); err != ErrStationNotFound {
+		t.Errorf("expected %s, but %v", ErrStationNotFound, err)
+	}
+}
+
+func TestRadioStation_playlistCreateURL(t *testing.T) {
+	station := RadioStation{StreamURLs: []StreamURL{
+		{PlaylistCreateURL: "live-areafree", Areafree: true},
+		{PlaylistCreateURL: "live"},
+		{PlaylistCreateURL: "tf-areafree", Areafree: true, Timefree: true},
+	}}
+	if expected, actual := "live", station.playlistCreateURL(false); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+	if expected, actual := "tf-areafree", station.playlistCreateURL(true); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+	if actual := (RadioStation{}).playlistCreateURL(true); actual != "" {
+		t.Errorf("expected empty, but %s", actual)
+	}
+}
+
+func TestProg_Performers(t *testing.T) {
+	cases := []struct {
+		pfm      string
+		expected []string
+	}{
+		{"", []string{}},
+		{"宇多丸", []string{"宇多丸"}},
+		{"宇多丸, 宇垣美里", []string{"宇多丸", "宇垣美里"}},
+		{"若林正恭、春日俊彰／ 佐藤満春 / ", []string{"若林正恭", "春日俊彰", "佐藤満春"}},
+	}
+	for _, c := range cases {
+		actual := Prog{Pfm: c.pfm}.Performers()
+		if actual == nil || strings.Join(actual, "|") != strings.Join(c.expected, "|") {
+			t.Errorf("%s: expected %q, but %q", c.pfm, c.expected, actual)
+		}
+	}
+}
+
+func TestProg_Hash(t *testing.T) {
+	p1 := Prog{MasterID: "1234", Ft: "20161112220000", To: "20161113000000", Title: "test"}
+	p2 := Prog{MasterID: "1234", Ft: "20161112220000", To: "20161113000000", Title: "test", Desc: "edited"}
+	if p1.Hash() != p2.Hash() {
+		t.Errorf("expected equal hashes, but %s and %s", p1.Hash(), p2.Hash())
+	}
+
+	p3 := Prog{MasterID: "1234", Ft: "20161112230000", To: "20161113010000", Title: "test"}
+	if p1.Hash() == p3.Hash() {
+		t.Errorf("expected different hashes, but %s", p1.Hash())
+	}
+
+	if expected, actual := 16, len(p1.Hash()); expected != actual {
+		t.Errorf("expected %d, but %d", expected, actual)
+	}
+}
+
+func TestFindProgramByStation_Fixture(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	date, err := util.ParseRadikoTime("20161112210000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := c.FindProgramByStation(context.Background(), "TBS", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "20161112200000"; expected != prog.Ft {
+		t.Errorf("expected %s, but %s", expected, prog.Ft)
+	}
+}
+
+func TestGetProgramNearStartTime(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+	c.SetTimeshiftTolerance(5 * time.Minute)
+
+	ft, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		start    time.Time
+		expected time.Duration
+	}{
+		{ft, 0},
+		{ft.Add(-90 * time.Second), 90 * time.Second},
+		{ft.Add(3 * time.Minute), -3 * time.Minute},
+	}
+	for _, cs := range cases {
+		prog, delta, err := c.GetProgramNearStartTime(context.Background(), "TBS", cs.start)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if expected := "20161112220000"; expected != prog.Ft {
+			t.Errorf("expected %s, but %s", expected, prog.Ft)
+		}
+		if cs.expected != delta {
+			t.Errorf("expected %s, but %s", cs.expected, delta)
+		}
+	}
+}
+
+func TestGetStationsWithArea(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	stations, area, err := c.GetStationsWithArea(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) == 0 {
+		t.Error("Stations is nil.")
+	}
+	if expected := (Area{ID: "JP13", Name: "TOKYO JAPAN"}); expected != area {
+		t.Errorf("expected %v, but %v", expected, area)
+	}
+}
+
+func TestDataCoverageOrdered(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs_missing.xml"))
+	defer closer()
+
+	list, err := c.DataCoverageOrdered(context.Background(), "JP13", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []StationCoverage{
+		{StationID: "TBS", Programs: 2},
+		{StationID: "QRR", Programs: 0},
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("expected %v, but %v", expected, list)
+	}
+	for i := range expected {
+		if expected[i] != list[i] {
+			t.Errorf("expected %v, but %v", expected[i], list[i])
+		}
+	}
+}
+
+func TestGetAllProgramsByDate(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs_missing.xml"))
+		case "/v3/program/station/date/20161112/QRR.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs_two_stations.xml"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+
+	date, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stations, err := c.GetAllProgramsByDate(context.Background(), date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) != 2 {
+		t.Fatalf("expected 2, but %d", len(stations))
+	}
+	for _, s := range stations {
+		if expected := 2; len(s.Progs.Progs) != expected {
+			t.Errorf("%s: expected %d, but %d", s.ID, expected, len(s.Progs.Progs))
+		}
+	}
+}
+
+func TestGetAllProgramsByDate_Error(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/program/date/20161112/JP13.xml" {
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs_missing.xml"))
+			return
+		}
+		w.Write([]byte("<radiko><stations>"))
+	}))
+	defer closer()
+
+	date, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetAllProgramsByDate(context.Background(), date); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestGetProgramsByDateRange(t *testing.T) {
+	days := map[string]string{
+		"20161112": `<prog id="1" ft="20161112050000" to="20161112280000"><title>1</title></prog>` +
+			`<prog id="2" ft="20161112280000" to="20161112290000"><title>2</title></prog>`,
+		// The program 2 also appears at the beginning of the next day.
+		"20161113": `<prog id="2" ft="20161113040000" to="20161113050000"><title>2</title></prog>` +
+			`<prog id="3" ft="20161113050000" to="20161113290000"><title>3</title></prog>`,
+	}
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var date string
+		if _, err := fmt.Sscanf(r.URL.Path, "/v3/program/station/date/%8s/TBS.xml", &date); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<radiko><stations><station id="TBS"><progs><date>%s</date>%s</progs></station></stations></radiko>`, date, days[date])
+	}))
+	defer closer()
+
+	from, err := util.ParseRadikoTime("20161112120000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 03:00 belongs to the broadcast day of 20161113.
+	to, err := util.ParseRadikoTime("20161114030000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	progs, err := c.GetProgramsByDateRange(context.Background(), "TBS", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, p := range progs {
+		ids = append(ids, p.ID)
+	}
+	if expected, actual := "1 2 3", strings.Join(ids, " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	if _, err := c.GetProgramsByDateRange(context.Background(), "TBS", to, from); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestProg_Clock(t *testing.T) {
+	cases := []struct {
+		ftl, tol             string
+		startClock, endClock string
+		startOffset          time.Duration
+	}{
+		{"0500", "0630", "05:00", "06:30", 5 * time.Hour},
+		{"500", "2400", "05:00", "00:00", 5 * time.Hour},
+		{"2330", "2545", "23:30", "01:45", 23*time.Hour + 30*time.Minute},
+		{"2500", "2900", "01:00", "05:00", 25 * time.Hour},
+		{"", "12", "", "", 0},
+	}
+	for _, c := range cases {
+		p := Prog{Ftl: c.ftl, Tol: c.tol}
+		if actual := p.StartClock(); c.startClock != actual {
+			t.Errorf("%s: expected %s, but %s", c.ftl, c.startClock, actual)
+		}
+		if actual := p.EndClock(); c.endClock != actual {
+			t.Errorf("%s: expected %s, but %s", c.tol, c.endClock, actual)
+		}
+		offset, err := p.StartOffset()
+		if c.startClock == "" {
+			if err == nil {
+				t.Error("Should detect an error.")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.startOffset != offset {
+			t.Errorf("%s: expected %s, but %s", c.ftl, c.startOffset, offset)
+		}
+	}
+
+	if _, err := (Prog{Tol: "2575"}).EndOffset(); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestProg_IsOnAir(t *testing.T) {
+	p := Prog{Ft: "20161112230000", To: "20161112250000"}
+
+	cases := []struct {
+		now      string
+		expected bool
+	}{
+		{"20161112225959", false},
+		{"20161112230000", true},
+		{"20161113003000", true},
+		{"20161113010000", false},
+	}
+	for _, c := range cases {
+		now, err := util.ParseRadikoTime(c.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := p.IsOnAir(now); c.expected != actual {
+			t.Errorf("%s: expected %t, but %t", c.now, c.expected, actual)
+		}
+	}
+
+	if (Prog{}).IsOnAir(time.Now()) {
+		t.Error("Prog without times should not be on the air.")
+	}
+}
+
+func TestProg_TimeshiftableRanges(t *testing.T) {
+	file, err := os.Open(filepath.Join(testdataDir, "program_ng.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var d stationsData
+	if err = decodeStationsData(file, &d); err != nil {
+		t.Fatal(err)
+	}
+	progs := d.programs("TBS")
+
+	parse := func(s string) time.Time {
+		tm, err := util.ParseRadikoTime(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	cases := []struct {
+		prog     Prog
+		expected []TimeRange
+	}{
+		{progs[0], []TimeRange{
+			{parse("20161112200000"), parse("20161112203000")},
+			{parse("20161112210000"), parse("20161112220000")},
+		}},
+		{progs[1], []TimeRange{
+			{parse("20161112220000"), parse("20161113000000")},
+		}},
+	}
+	for _, c := range cases {
+		ranges := c.prog.TimeshiftableRanges()
+		if len(ranges) != len(c.expected) {
+			t.Errorf("expected %v, but %v", c.expected, ranges)
+			continue
+		}
+		for i := range ranges {
+			if !ranges[i].Start.Equal(c.expected[i].Start) || !ranges[i].End.Equal(c.expected[i].End) {
+				t.Errorf("expected %v, but %v", c.expected[i], ranges[i])
+			}
+		}
+	}
+}
+
+func TestProg_CanTimeshift(t *testing.T) {
+	file, err := os.Open(filepath.Join(testdataDir, "program_ts_ng.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var d stationsData
+	if err = decodeStationsData(file, &d); err != nil {
+		t.Fatal(err)
+	}
+	progs := d.programs("TBS")
+	if expected := 3; len(progs) != expected {
+		t.Fatalf("expected %d programs, but %d", expected, len(progs))
+	}
+
+	cases := []struct {
+		prog              Prog
+		expectedTimeshift bool
+		expectedShare     bool
+	}{
+		{progs[0], true, true},
+		{progs[1], true, false},
+		{progs[2], false, false},
+	}
+	for _, c := range cases {
+		if actual := c.prog.CanTimeshift(); c.expectedTimeshift != actual {
+			t.Errorf("%s: expected %v, but %v", c.prog.ID, c.expectedTimeshift, actual)
+		}
+		if actual := c.prog.CanShare(); c.expectedShare != actual {
+			t.Errorf("%s: expected %v, but %v", c.prog.ID, c.expectedShare, actual)
+		}
+		if actual := c.prog.ShareURL("TBS"); c.expectedShare != (actual != "") {
+			t.Errorf("%s: unexpected share url %q", c.prog.ID, actual)
+		}
+	}
+}
+
+func TestProg_DurationConsistent(t *testing.T) {
+	cases := []struct {
+		prog     Prog
+		expected bool
+	}{
+		{Prog{Ft: "20161112220000", To: "20161113000000", Dur: "7200"}, true},
+		{Prog{Ft: "20161112220000", To: "20161113000000", Dur: "7230"}, true},
+		{Prog{Ft: "20161112220000", To: "20161113000000", Dur: "3600"}, false},
+		{Prog{Ft: "20161112220000", To: "20161113000000", Dur: ""}, false},
+		{Prog{Ft: "", To: "20161113000000", Dur: "7200"}, false},
+	}
+	for _, c := range cases {
+		if actual := c.prog.DurationConsistent(); c.expected != actual {
+			t.Errorf("%s-%s (%s): expected %v, but %v", c.prog.Ft, c.prog.To, c.prog.Dur, c.expected, actual)
+		}
+	}
+}
+
+func TestProg_StartTimeEndTime(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	p := Prog{Ft: "20161112230000", To: "20161112250000"}
+
+	start, err := p.StartTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2016, 11, 12, 23, 0, 0, 0, jst); !expected.Equal(start) {
+		t.Errorf("expected %s, but %s", expected, start)
+	}
+	end, err := p.EndTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2016, 11, 13, 1, 0, 0, 0, jst); !expected.Equal(end) {
+		t.Errorf("expected %s, but %s", expected, end)
+	}
+
+	for _, invalid := range []string{"", "2016111223", "2016111223000a"} {
+		p := Prog{Ft: invalid, To: invalid}
+		if _, err := p.StartTime(); err == nil {
+			t.Errorf("%q: Should detect an error.", invalid)
+		}
+		if _, err := p.EndTime(); err == nil {
+			t.Errorf("%q: Should detect an error.", invalid)
+		}
+	}
+}
+
+func TestProg_BroadcastDate(t *testing.T) {
+	cases := []struct {
+		ft       string
+		expected string
+	}{
+		{"20240115045900", "20240114"},
+		{"20240115050000", "20240115"},
+		{"20240115235900", "20240115"},
+		{"20240115000000", "20240114"},
+		{"20240114283000", "20240114"},
+		{"20240114290000", "20240115"},
+	}
+	for _, c := range cases {
+		actual, err := (Prog{Ft: c.ft}).BroadcastDate()
+		if err != nil {
+			t.Errorf("%s: %s", c.ft, err)
+			continue
+		}
+		expected, _ := time.ParseInLocation("20060102", c.expected, util.Location())
+		if !expected.Equal(actual) {
+			t.Errorf("%s: expected %v, but %v", c.ft, expected, actual)
+		}
+	}
+
+	if _, err := (Prog{Ft: "invalid"}).BroadcastDate(); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestProg_Duration(t *testing.T) {
+	cases := []struct {
+		prog     Prog
+		expected time.Duration
+	}{
+		{Prog{Ft: "20161112220000", To: "20161113000000", Dur: "7200"}, 2 * time.Hour},
+		{Prog{Ft: "20161112220000", To: "20161113000000", Dur: ""}, 2 * time.Hour},
+		{Prog{Ft: "20161112230000", To: "20161112253000", Dur: "0"}, 150 * time.Minute},
+		{Prog{Dur: "1800"}, 30 * time.Minute},
+	}
+	for _, c := range cases {
+		actual, err := c.prog.Duration()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if c.expected != actual {
+			t.Errorf("expected %s, but %s", c.expected, actual)
+		}
+	}
+
+	for _, p := range []Prog{{}, {Dur: "abc"}, {Ft: "20161112220000", To: "invalid"}} {
+		if _, err := p.Duration(); err == nil {
+			t.Errorf("%+v: Should detect an error.", p)
+		}
+	}
+}
+
+func TestGetProgramByStartTime_Fixture(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	// The matched program is not the last one of the station.
+	for _, ft := range []string{"20161112200000", "20161112220000"} {
+		start, err := util.ParseRadikoTime(ft)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prog, err := c.GetProgramByStartTime(context.Background(), "TBS", start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ft != prog.Ft {
+			t.Errorf("expected %s, but %s", ft, prog.Ft)
+		}
+	}
+}
+
+func TestGetProgramsByStation_MultipleStations(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs_two_stations.xml"))
+	defer closer()
+
+	progs, err := c.GetProgramsByStation(context.Background(), "TBS", programsFixtureDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, p := range progs {
+		ids = append(ids, p.ID)
+	}
+	if expected, actual := "10001 10002", strings.Join(ids, " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	if _, err := c.GetProgramsByStation(context.Background(), "LFR", time.Now()); err != ErrStationNotFound {
+		t.Errorf("expected %s, but %v", ErrStationNotFound, err)
+	}
+}
+
+func TestGetProgramsByStation_Midnight(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs_midnight.xml"))
+	defer closer()
+
+	// 01:00 on 13th is in the broadcast day of 12th.
+	date := time.Date(2016, 11, 13, 1, 0, 0, 0, util.Location())
+	progs, err := c.GetProgramsByStation(context.Background(), "TBS", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, p := range progs {
+		ids = append(ids, p.ID)
+	}
+	if expected, actual := "30001 30002 30003", strings.Join(ids, " "); expected != actual {
+		t.Fatalf("expected %s, but %s", expected, actual)
+	}
+	for i := 1; i < len(progs); i++ {
+		if progs[i-1].To != progs[i].Ft {
+			t.Errorf("gap between %s and %s", progs[i-1].To, progs[i].Ft)
+		}
+	}
+}
+
+func TestGetProgramsByStation_NoStations(t *testing.T) {
+	const empty = `<?xml version="1.0" encoding="UTF-8"?>
+<radiko>
+  <ttl>1800</ttl>
+  <srvtime>1478960627</srvtime>
+  <stations></stations>
+</radiko>`
+
+	var d stationsData
+	if err := decodeStationsData(strings.NewReader(empty), &d); err != nil {
+		t.Fatal(err)
+	}
+	if progs := d.programs("TBS"); len(progs) != 0 {
+		t.Errorf("expected no programs, but %d", len(progs))
+	}
+
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(empty))
+	}))
+	defer closer()
+
+	_, err := c.GetProgramsByStation(context.Background(), "INVALID", time.Now())
+	if err != ErrStationNotFound {
+		t.Errorf("expected %s, but %v", ErrStationNotFound, err)
+	}
+}
+
+func TestGetStationsRaw(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	stations, raw, err := c.GetStationsRaw(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) == 0 {
+		t.Error("Stations is nil.")
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(testdataDir, "programs.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(raw) {
+		t.Errorf("expected %s, but %s", b, raw)
+	}
+}
+
+func TestStations_FindByID(t *testing.T) {
+	s := Stations{
+		{ID: "TBS", Name: "TBSラジオ"},
+		{ID: "QRR", Name: "文化放送"},
+		{ID: "LFR", Name: "ニッポン放送"},
+	}
+
+	station, ok := s.FindByID("QRR")
+	if !ok {
+		t.Fatal("QRR is not found.")
+	}
+	if expected := "文化放送"; expected != station.Name {
+		t.Errorf("expected %s, but %s", expected, station.Name)
+	}
+	if station, ok := s.FindByID("FMT"); ok || station != nil {
+		t.Errorf("expected nil, but %v", station)
+	}
+
+	if expected, actual := "TBS LFR", strings.Join(s.FilterByIDs("LFR", "TBS", "FMT").IDs(), " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	s.SortByName()
+	if expected, actual := "TBS LFR QRR", strings.Join(s.IDs(), " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+}
+
+func TestValidateStationID(t *testing.T) {
+	for _, id := range []string{"TBS", "RN1", "ALPHA-STATION", "JOAK_FM"} {
+		if err := ValidateStationID(id); err != nil {
+			t.Errorf("%s: unexpected error: %s", id, err)
+		}
+	}
+	for _, id := range []string{"", "tbs", "TBS.xml", "../TBS", " TBS", "-TBS"} {
+		if err := ValidateStationID(id); !errors.Is(err, ErrInvalidStationID) {
+			t.Errorf("%q: expected %s, but %v", id, ErrInvalidStationID, err)
+		}
+	}
+}
+
+func TestGetProgramsByStation_InvalidStationID(t *testing.T) {
+	var requested bool
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer closer()
+
+	if _, err := c.GetProgramsByStation(context.Background(), "TBS/../LFR", time.Now()); !errors.Is(err, ErrInvalidStationID) {
+		t.Errorf("expected %s, but %v", ErrInvalidStationID, err)
+	}
+	if _, err := c.GetWeeklyPrograms(context.Background(), "tbs"); !errors.Is(err, ErrInvalidStationID) {
+		t.Errorf("expected %s, but %v", ErrInvalidStationID, err)
+	}
+	if requested {
+		t.Error("invalid station id should not be requested")
+	}
+}
+
+func TestGetStationByID(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("station_list.xml"))
+	defer closer()
+
+	station, err := c.GetStationByID(context.Background(), "QRR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "文化放送"; expected != station.Name {
+		t.Errorf("expected %s, but %s", expected, station.Name)
+	}
+
+	for _, id := range []string{"qrr", "LFR"} {
+		if _, err = c.GetStationByID(context.Background(), id); err != ErrStationNotFound {
+			t.Errorf("%s: expected %s, but %v", id, ErrStationNotFound, err)
+		}
+	}
+	if _, err = c.GetStationByID(context.Background(), ""); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestGetStationsForArea(t *testing.T) {
+	var requested []string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v3/station/list/"):
+			http.ServeFile(w, r, filepath.Join(testdataDir, "station_list.xml"))
+		default:
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		}
+	}))
+	defer closer()
+
+	date, err := util.ParseRadikoTime("20161112210000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.GetStationsForArea(context.Background(), "JP27", date); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.GetRadioStationsForArea(context.Background(), "JP27"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"/v3/program/date/20161112/JP27.xml",
+		"/v3/station/list/JP27.xml",
+	}
+	if strings.Join(expected, ",") != strings.Join(requested, ",") {
+		t.Errorf("expected %v, but %v", expected, requested)
+	}
+
+	if _, err = c.GetStationsForArea(context.Background(), "", date); err == nil {
+		t.Error("Should detect an error.")
+	}
+	if _, err = c.GetRadioStationsForArea(context.Background(), ""); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestGetProgramByID(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+	}))
+	defer closer()
+
+	prog, err := c.GetProgramByID(context.Background(), "20001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "20161112230000"; expected != prog.Ft {
+		t.Errorf("expected %s, but %s", expected, prog.Ft)
+	}
+	if expected := 2*timeshiftDays + 1; requests != expected {
+		t.Errorf("expected %d requests, but %d", expected, requests)
+	}
+
+	if _, err = c.GetProgramByID(context.Background(), "99999"); err != ErrProgramNotFound {
+		t.Errorf("expected %s, but %v", ErrProgramNotFound, err)
+	}
+	if _, err = c.GetProgramByID(context.Background(), ""); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
@@ -0,0 +1,104 @@
+package radikotest
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Station is a station served by Server.
+type Station struct {
+	ID   string
+	Name string
+}
+
+// Stations are the stations served by Server in AreaID.
+var Stations = []Station{
+	{ID: "TBS", Name: "TBSラジオ"},
+	{ID: "QRR", Name: "文化放送"},
+}
+
+// Program is a program broadcast every day by all the Stations.
+type Program struct {
+	Title string
+	// Start and End are the times of day formatted as hhmm.
+	// The hours from 24 are after midnight, like radiko.
+	Start string
+	End   string
+}
+
+// Schedule is the programs broadcast every day.
+// It covers the broadcast day from 5:00 AM to 5:00 AM of the next day.
+var Schedule = []Program{
+	{Title: "Morning", Start: "0500", End: "1200"},
+	{Title: "Afternoon", Start: "1200", End: "1800"},
+	{Title: "Night", Start: "1800", End: "2900"},
+}
+
+// Segments is the number of the segments in the timeshift chunklist.
+const Segments = 3
+
+func findStation(id string) (Station, bool) {
+	for _, s := range Stations {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return Station{}, false
+}
+
+func writeStationList(w io.Writer, areaID string) {
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<stations area_id="%s" area_name="TOKYO JAPAN">
+`, areaID)
+	if areaID == AreaID {
+		for _, s := range Stations {
+			fmt.Fprintf(w, `  <station>
+    <id>%s</id>
+    <name>%s</name>
+    <areafree>1</areafree>
+    <timefree>1</timefree>
+    <area_id>%s</area_id>
+  </station>
+`, s.ID, s.Name, AreaID)
+		}
+	}
+	fmt.Fprint(w, "</stations>\n")
+}
+
+// writePrograms writes the programs of the stations for the dates
+// in the program API format.
+func writePrograms(w io.Writer, stations []Station, dates []string) {
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<radiko>
+  <ttl>1800</ttl>
+  <srvtime>%d</srvtime>
+  <stations area_id="%s" area_name="TOKYO JAPAN">
+`, time.Now().Unix(), AreaID)
+	for _, s := range stations {
+		fmt.Fprintf(w, "    <station id=\"%s\">\n      <name>%s</name>\n", s.ID, s.Name)
+		for _, date := range dates {
+			writeProgs(w, s, date)
+		}
+		fmt.Fprint(w, "    </station>\n")
+	}
+	fmt.Fprint(w, "  </stations>\n</radiko>\n")
+}
+
+func writeProgs(w io.Writer, s Station, date string) {
+	fmt.Fprintf(w, "      <progs>\n        <date>%s</date>\n", date)
+	for i, p := range Schedule {
+		fmt.Fprintf(w, `        <prog id="%s%s%d" master_id="" ft="%s%s00" to="%s%s00" ftl="%s" tol="%s" dur="%d">
+          <title>%s</title>
+        </prog>
+`, s.ID, date, i, date, p.Start, date, p.End, p.Start, p.End, minutes(p.End)*60-minutes(p.Start)*60, p.Title)
+	}
+	fmt.Fprint(w, "      </progs>\n")
+}
+
+// minutes returns the minutes since midnight of hhmm.
+func minutes(hhmm string) int {
+	n, _ := strconv.Atoi(hhmm)
+	return n/100*60 + n%100
+}
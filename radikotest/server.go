@@ -0,0 +1,128 @@
+// Package radikotest provides a fake radiko server for testing
+// the code using go-radiko without accessing radiko.jp.
+package radikotest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"time"
+
+	radiko "github.com/chikulla/go-radiko"
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+const (
+	// AreaID is the area of the Client returned by NewClient.
+	AreaID = "JP13"
+	// AuthToken is the auth token accepted by Server.
+	AuthToken = "radikotest-token"
+)
+
+// Server is a fake radiko server which serves the canned responses.
+// The program schedules of any date are generated from Schedule.
+type Server struct {
+	*httptest.Server
+}
+
+// NewServer starts and returns a new Server.
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// NewClient returns a Client which sends requests to the server.
+// The options are applied after the ones for the server.
+func (s *Server) NewClient(opts ...radiko.Option) (*radiko.Client, error) {
+	opts = append([]radiko.Option{
+		radiko.WithBaseURL(s.URL),
+		radiko.WithAreaID(AreaID),
+		radiko.WithHTTPClient(s.Client()),
+	}, opts...)
+	return radiko.New(AuthToken, opts...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	dir, file := path.Split(r.URL.Path)
+	name := strings.TrimSuffix(file, path.Ext(file))
+
+	switch {
+	case dir == "/v3/station/list/":
+		writeStationList(w, name)
+	case strings.HasPrefix(dir, "/v3/program/date/"):
+		date := path.Base(dir)
+		if !validDate(date) || name != AreaID {
+			http.NotFound(w, r)
+			return
+		}
+		writePrograms(w, Stations, []string{date})
+	case strings.HasPrefix(dir, "/v3/program/station/date/"):
+		date := path.Base(dir)
+		station, ok := findStation(name)
+		if !validDate(date) || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writePrograms(w, []Station{station}, []string{date})
+	case dir == "/v3/program/station/weekly/":
+		station, ok := findStation(name)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writePrograms(w, []Station{station}, weekDates(time.Now()))
+	case r.URL.Path == "/v2/api/program/now":
+		writePrograms(w, Stations, []string{util.ProgramsDate(time.Now())})
+	case r.URL.Path == "/v2/api/ts/playlist.m3u8":
+		s.servePlaylist(w, r)
+	case r.URL.Path == "/radikotest/chunklist.m3u8":
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:1\n")
+		for i := 0; i < Segments; i++ {
+			fmt.Fprintf(w, "#EXTINF:5,\nsegment/%d.aac\n", i)
+		}
+		fmt.Fprint(w, "#EXT-X-ENDLIST\n")
+	case dir == "/radikotest/segment/":
+		fmt.Fprintf(w, "radikotest segment %s\n", name)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// servePlaylist serves the timeshift playlist of the program
+// which is in Schedule and has already ended.
+func (s *Server) servePlaylist(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Radiko-AuthToken") != AuthToken {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	q := r.URL.Query()
+	if _, ok := findStation(q.Get("station_id")); !ok {
+		http.NotFound(w, r)
+		return
+	}
+	to, err := util.ParseRadikoTime(q.Get("to"))
+	if err != nil || to.After(time.Now()) {
+		http.NotFound(w, r)
+		return
+	}
+
+	fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=52973,CODECS=\"mp4a.40.5\"\n%s/radikotest/chunklist.m3u8\n", s.URL)
+}
+
+func validDate(date string) bool {
+	_, err := time.Parse("20060102", date)
+	return err == nil
+}
+
+// weekDates returns the broadcast dates from 6 days ago to the day of now.
+func weekDates(now time.Time) []string {
+	dates := make([]string, 0, 7)
+	for i := -6; i <= 0; i++ {
+		dates = append(dates, util.ProgramsDate(now.AddDate(0, 0, i)))
+	}
+	return dates
+}
@@ -0,0 +1,63 @@
+package radikotest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	c, err := s.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.AreaID() != AreaID {
+		t.Errorf("expected %s, but %s", AreaID, c.AreaID())
+	}
+	ctx := context.Background()
+
+	stations, err := c.GetStations(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) != len(Stations) {
+		t.Fatalf("expected %d, but %d", len(Stations), len(stations))
+	}
+	if expected := len(Schedule); len(stations[0].Progs.Progs) != expected {
+		t.Errorf("expected %d, but %d", expected, len(stations[0].Progs.Progs))
+	}
+
+	weekly, err := c.GetWeeklyPrograms(ctx, "TBS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 7 * len(Schedule); len(weekly[0].Progs.Progs) != expected {
+		t.Errorf("expected %d, but %d", expected, len(weekly[0].Progs.Progs))
+	}
+	if _, err := c.GetWeeklyPrograms(ctx, "LFR"); err == nil {
+		t.Error("Should detect an error.")
+	}
+
+	yesterday := util.ProgramsDate(time.Now().AddDate(0, 0, -1))
+	start, err := util.ParseRadikoTime(yesterday + Schedule[1].Start + "00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TimeshiftPlaylistM3U8(ctx, "TBS", start); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.DownloadTimeshift(ctx, "TBS", start, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "radikotest segment 0\nradikotest segment 1\nradikotest segment 2\n"; expected != buf.String() {
+		t.Errorf("expected %q, but %q", expected, buf.String())
+	}
+}
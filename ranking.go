@@ -0,0 +1,62 @@
+package radiko
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+)
+
+// RankedProg is a program in the ranking.
+type RankedProg struct {
+	Prog
+	Rank      int    `xml:"rank,attr"`
+	StationID string `xml:"station_id,attr"`
+}
+
+// GetRankingPrograms returns the popular programs in the area,
+// sorted by the rank.
+func (c *Client) GetRankingPrograms(ctx context.Context, areaID string) ([]RankedProg, error) {
+	if areaID == "" {
+		return nil, errors.New("AreaID is empty")
+	}
+
+	apiEndpoint := path.Join(apiV3, "feed/ranking", fmt.Sprintf("%s.xml", areaID))
+
+	var d rankingData
+	err := c.getXML(ctx, apiEndpoint, &Params{}, func(r io.Reader) error {
+		d = rankingData{}
+		return decodeRankingData(r, &d)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	progs := d.Progs
+	sort.SliceStable(progs, func(i, j int) bool {
+		return progs[i].Rank < progs[j].Rank
+	})
+	return progs, nil
+}
+
+// rankingData includes a response struct for client's users.
+type rankingData struct {
+	XMLName xml.Name     `xml:"ranking"`
+	AreaID  string       `xml:"area_id,attr"`
+	Progs   []RankedProg `xml:"prog"`
+}
+
+func decodeRankingData(input io.Reader, ranking *rankingData) error {
+	b, err := ioutil.ReadAll(input)
+	if err != nil {
+		return err
+	}
+	if err = xml.Unmarshal(b, ranking); err != nil {
+		return err
+	}
+	return nil
+}
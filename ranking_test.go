@@ -0,0 +1,54 @@
+package radiko
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetRankingPrograms(t *testing.T) {
+	var requested string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		http.ServeFile(w, r, filepath.Join(testdataDir, "ranking.xml"))
+	}))
+	defer closer()
+
+	progs, err := c.GetRankingPrograms(context.Background(), "JP13")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/v3/feed/ranking/JP13.xml"; expected != requested {
+		t.Errorf("expected %s, but %s", expected, requested)
+	}
+
+	expected := []struct {
+		rank      int
+		stationID string
+	}{
+		{1, "TBS"},
+		{2, "QRR"},
+		{3, "LFR"},
+	}
+	if len(progs) != len(expected) {
+		t.Fatalf("expected %d programs, but %d", len(expected), len(progs))
+	}
+	for i, e := range expected {
+		if progs[i].Rank != e.rank || progs[i].StationID != e.stationID {
+			t.Errorf("expected %d %s, but %d %s", e.rank, e.stationID, progs[i].Rank, progs[i].StationID)
+		}
+	}
+	if expected := "ライムスター宇多丸のウィークエンド・シャッフル"; expected != progs[0].Title {
+		t.Errorf("expected %s, but %s", expected, progs[0].Title)
+	}
+}
+
+func TestGetRankingPrograms_EmptyAreaID(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("ranking.xml"))
+	defer closer()
+
+	if _, err := c.GetRankingPrograms(context.Background(), ""); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
@@ -0,0 +1,104 @@
+package radiko
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// getXML sends a GET request to apiEndpoint and decodes the response body.
+// decode may be called more than once, so it should reset its result.
+// If the response is truncated, the request is retried
+// as the network errors are by WithRetry.
+func (c *Client) getXML(ctx context.Context, apiEndpoint string, params *Params, decode func(io.Reader) error) error {
+	for attempt := 1; ; attempt++ {
+		err := c.getXMLOnce(ctx, apiEndpoint, params, decode)
+		if err == nil || !isTruncated(err) || attempt >= c.retryAttempts || ctx.Err() != nil {
+			return err
+		}
+		c.logf("radiko: truncated response from %s, retrying: %s", apiEndpoint, err)
+
+		select {
+		case <-time.After(backoff(c.retryBaseDelay, attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *Client) getXMLOnce(ctx context.Context, apiEndpoint string, params *Params, decode func(io.Reader) error) error {
+	req, err := c.newRequest(ctx, "GET", apiEndpoint, params)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return readAPIError(resp)
+	}
+	return decode(resp.Body)
+}
+
+// isTruncated reports whether err is caused by a truncated response,
+// distinguished from a malformed XML.
+func isTruncated(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var syntaxErr *xml.SyntaxError
+	return errors.As(err, &syntaxErr) && syntaxErr.Msg == "unexpected EOF"
+}
+
+// doWithRetry sends req up to c.retryAttempts times
+// until it succeeds without a network error or 5xx.
+func (c *Client) doWithRetry(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(req)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			return resp, nil
+		}
+		if attempt >= c.retryAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		if err != nil {
+			c.logf("radiko: %s %s failed, retrying: %s", req.Method, req.URL, err)
+		} else {
+			c.logf("radiko: %s %s returned %d, retrying", req.Method, req.URL, resp.StatusCode)
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(backoff(c.retryBaseDelay, attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt.
+// The delay is between half and all of baseDelay * 2^(attempt-1).
+func backoff(baseDelay time.Duration, attempt int) time.Duration {
+	d := baseDelay << uint(attempt-1)
+	if d <= 0 {
+		return baseDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// isIdempotent reports whether the request of method can be retried safely.
+func isIdempotent(method string) bool {
+	return method == "GET" || method == "HEAD" || method == ""
+}
@@ -0,0 +1,238 @@
+package radiko
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetRadioStations_RetryTruncated(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join(testdataDir, "station_list.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int32
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Write(b[:len(b)/2])
+			return
+		}
+		w.Write(b)
+	}))
+	defer closer()
+	if err := WithRetry(2, 0)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	stations, err := c.GetRadioStations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 3; len(stations) != expected {
+		t.Errorf("expected %d stations, but %d", expected, len(stations))
+	}
+	if expected := int32(2); requests != expected {
+		t.Errorf("expected %d requests, but %d", expected, requests)
+	}
+}
+
+func TestGetRadioStations_NoRetryMalformed(t *testing.T) {
+	var requests int32
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><stations><station></stations>`))
+	}))
+	defer closer()
+	if err := WithRetry(2, 0)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.GetRadioStations(context.Background()); err == nil {
+		t.Error("Should detect an error.")
+	}
+	if expected := int32(1); requests != expected {
+		t.Errorf("expected %d requests, but %d", expected, requests)
+	}
+}
+
+func TestIsTruncated(t *testing.T) {
+	var d radioStationsData
+	truncated := decodeRadioStationsData(
+		strings.NewReader(`<?xml version="1.0"?><stations><station><id>TBS`), &d)
+	malformed := decodeRadioStationsData(
+		strings.NewReader(`<?xml version="1.0"?><stations><station></stations>`), &d)
+
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{truncated, true},
+		{malformed, false},
+		{errors.New("other"), false},
+	}
+	for _, c := range cases {
+		if actual := isTruncated(c.err); c.expected != actual {
+			t.Errorf("%v: expected %v, but %v", c.err, c.expected, actual)
+		}
+	}
+}
+
+func TestDo_Retry(t *testing.T) {
+	var requests int32
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer closer()
+	if err := WithRetry(3, time.Millisecond)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := c.newRequest(context.Background(), "GET", "", &Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %d, but %d", http.StatusOK, resp.StatusCode)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3, but %d", requests)
+	}
+}
+
+func TestDo_RetryExhausted(t *testing.T) {
+	var requests int32
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer closer()
+	if err := WithRetry(2, time.Millisecond)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := c.newRequest(context.Background(), "GET", "", &Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected %d, but %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2, but %d", requests)
+	}
+}
+
+func TestDo_NoRetryPOST(t *testing.T) {
+	var requests int32
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer closer()
+	if err := WithRetry(3, time.Millisecond)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := c.newRequest(context.Background(), "POST", "", &Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if requests != 1 {
+		t.Errorf("expected 1, but %d", requests)
+	}
+}
+
+func TestDo_RetryCanceled(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer closer()
+	if err := WithRetry(3, time.Hour)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := c.newRequest(ctx, "GET", "", &Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %s, but %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestWithRetry_Invalid(t *testing.T) {
+	c, closer := newTestClient(t, http.NotFoundHandler())
+	defer closer()
+
+	if err := WithRetry(0, time.Second)(c); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestGetWeeklyProgramsStream_RetryTruncated(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join(testdataDir, "programs.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cut the response after the first station.
+	cut := strings.Index(string(b), "</station>") + len("</station>") + 1
+
+	var requests int32
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Write(b[:cut])
+			return
+		}
+		w.Write(b)
+	}))
+	defer closer()
+	if err := WithRetry(2, 0)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	err = c.GetWeeklyProgramsStream(context.Background(), "TBS", func(s Station) error {
+		ids = append(ids, s.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := int32(2); requests != expected {
+		t.Errorf("expected %d requests, but %d", expected, requests)
+	}
+	if expected := 2; len(ids) != expected || ids[0] == ids[1] {
+		t.Errorf("expected %d distinct stations, but %v", expected, ids)
+	}
+}
@@ -0,0 +1,98 @@
+package radiko
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"time"
+)
+
+const itunesNamespace = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+type rss struct {
+	XMLName     xml.Name   `xml:"rss"`
+	Version     string     `xml:"version,attr"`
+	XMLNSItunes string     `xml:"xmlns:itunes,attr"`
+	Channel     rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	Language    string       `xml:"language"`
+	Author      string       `xml:"itunes:author"`
+	Image       *itunesImage `xml:"itunes:image,omitempty"`
+	Items       []rssItem    `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link,omitempty"`
+	Description string       `xml:"description,omitempty"`
+	GUID        rssGUID      `xml:"guid"`
+	PubDate     string       `xml:"pubDate,omitempty"`
+	Author      string       `xml:"itunes:author,omitempty"`
+	Duration    string       `xml:"itunes:duration,omitempty"`
+	Image       *itunesImage `xml:"itunes:image,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// ProgramsToRSS returns the programs as an RSS 2.0 feed
+// with the iTunes podcast extensions.
+// Each item links to the timeshift page of the program.
+// The missing fields of the programs are omitted from the items.
+func ProgramsToRSS(station RadioStation, progs []Prog) ([]byte, error) {
+	doc := rss{
+		Version:     "2.0",
+		XMLNSItunes: itunesNamespace,
+		Channel: rssChannel{
+			Title:       station.Name,
+			Link:        GetLiveURL(station.ID),
+			Description: station.Name,
+			Language:    "ja",
+			Author:      station.Name,
+			Items:       make([]rssItem, 0, len(progs)),
+		},
+	}
+	if logo := station.LargestLogo(); logo != "" {
+		doc.Channel.Image = &itunesImage{Href: logo}
+	}
+
+	for _, p := range progs {
+		item := rssItem{
+			Title:       p.Title,
+			Link:        p.TimeshiftURL(station.ID),
+			Description: stripHTML(p.Desc),
+			GUID:        rssGUID{Value: station.ID + "/" + p.Ft},
+			Author:      p.Pfm,
+		}
+		if ft, err := p.StartTime(); err == nil {
+			item.PubDate = ft.Format(time.RFC1123Z)
+		}
+		if d, err := p.Duration(); err == nil && d > 0 {
+			item.Duration = strconv.Itoa(int(d / time.Second))
+		}
+		if p.ImageURL != "" {
+			item.Image = &itunesImage{Href: p.ImageURL}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
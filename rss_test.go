@@ -0,0 +1,57 @@
+package radiko
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestProgramsToRSS(t *testing.T) {
+	station := RadioStation{
+		ID:    "TBS",
+		Name:  "TBSラジオ",
+		Logos: []Logo{{Width: 224, Height: 100, URL: "https://radiko.jp/v2/static/station/logo/TBS/224x100.png"}},
+	}
+	progs := []Prog{
+		{
+			Ft:       "20161112220000",
+			To:       "20161113000000",
+			Dur:      "7200",
+			Title:    "ライムスター宇多丸のウィークエンド・シャッフル",
+			Desc:     "<p>映画評</p>",
+			Pfm:      "宇多丸",
+			ImageURL: "https://radiko.jp/res/program/DEFAULT_IMAGE/TBS/utamaru.jpg",
+		},
+		// Only the title.
+		{Title: "unknown"},
+	}
+
+	b, err := ProgramsToRSS(station, progs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := string(b)
+
+	for _, expected := range []string{
+		`<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">`,
+		`<itunes:image href="https://radiko.jp/v2/static/station/logo/TBS/224x100.png"></itunes:image>`,
+		`<link>https://radiko.jp/#!/ts/TBS/20161112220000</link>`,
+		`<description>映画評</description>`,
+		`<pubDate>Sat, 12 Nov 2016 22:00:00 +0900</pubDate>`,
+		`<itunes:author>宇多丸</itunes:author>`,
+		`<itunes:duration>7200</itunes:duration>`,
+		`<title>unknown</title>`,
+	} {
+		if !strings.Contains(feed, expected) {
+			t.Errorf("expected %s in %s", expected, feed)
+		}
+	}
+
+	var doc rss
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if expected := 2; len(doc.Channel.Items) != expected {
+		t.Errorf("expected %d items, but %d", expected, len(doc.Channel.Items))
+	}
+}
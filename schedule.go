@@ -0,0 +1,653 @@
+package radiko
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+// ProgWithStation is a program with the station which broadcasts it.
+type ProgWithStation struct {
+	StationID   string
+	StationName string
+	Prog        Prog
+}
+
+// StartingWithin returns the programs whose start time is in (now, now+d],
+// sorted by the start time.
+func (s Stations) StartingWithin(now time.Time, d time.Duration) []ProgWithStation {
+	end := now.Add(d)
+
+	var progs []ProgWithStation
+	for _, station := range s {
+		for _, p := range station.programs() {
+			ft, err := util.ParseRadikoTime(p.Ft)
+			if err != nil {
+				continue
+			}
+			if ft.After(now) && !ft.After(end) {
+				progs = append(progs, ProgWithStation{
+					StationID:   station.ID,
+					StationName: station.Name,
+					Prog:        p,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(progs, func(i, j int) bool {
+		return progs[i].Prog.Ft < progs[j].Prog.Ft
+	})
+	return progs
+}
+
+// programs returns the station's programs
+// in both of the progs and the scd elements.
+func (s Station) programs() []Prog {
+	progs := make([]Prog, 0, len(s.Progs.Progs)+len(s.Scd.Progs.Progs))
+	progs = append(progs, s.Progs.Progs...)
+	return append(progs, s.Scd.Progs.Progs...)
+}
+
+// Grid returns the programs of each station keyed by the station id,
+// sorted by the start time.
+func (s Stations) Grid() map[string][]Prog {
+	grid := make(map[string][]Prog, len(s))
+	for _, station := range s {
+		grid[station.ID] = station.sortedPrograms()
+	}
+	return grid
+}
+
+// Merge returns the union of s and other by the station id,
+// in the order that the stations first appear.
+// The programs of the same station are deduplicated as Dedup does.
+// s and other are not modified.
+func (s Stations) Merge(other Stations) Stations {
+	all := make(Stations, 0, len(s)+len(other))
+	all = append(all, s...)
+	return append(all, other...).Dedup()
+}
+
+// Dedup returns the stations which the same station id appears once,
+// in the order that the stations first appear.
+// The programs of the same station, including the ones in Scd,
+// are put into Progs, deduplicated by Ft and sorted by the start time.
+// If two programs share Ft, the one with more fields set is kept.
+// The name and the logos are of the first station which has them.
+func (s Stations) Dedup() Stations {
+	var (
+		merged = make(Stations, 0, len(s))
+		index  = make(map[string]int, len(s))
+		// byFt maps the Ft to the index in the station's programs.
+		byFt = make([]map[string]int, 0, len(s))
+	)
+	for _, station := range s {
+		i, ok := index[station.ID]
+		if !ok {
+			i = len(merged)
+			index[station.ID] = i
+			merged = append(merged, Station{ID: station.ID, Progs: Progs{Progs: []Prog{}}})
+			byFt = append(byFt, make(map[string]int))
+		}
+
+		m := &merged[i]
+		if m.Name == "" {
+			m.Name = station.Name
+		}
+		if len(m.Logos) == 0 {
+			m.Logos = station.Logos
+		}
+		if m.Progs.Date == "" {
+			m.Progs.Date = station.Progs.Date
+		}
+
+		for _, p := range station.programs() {
+			j, ok := byFt[i][p.Ft]
+			if !ok {
+				byFt[i][p.Ft] = len(m.Progs.Progs)
+				m.Progs.Progs = append(m.Progs.Progs, p)
+				continue
+			}
+			if p.richness() > m.Progs.Progs[j].richness() {
+				m.Progs.Progs[j] = p
+			}
+		}
+	}
+
+	for i := range merged {
+		merged[i].Progs.Sort()
+	}
+	return merged
+}
+
+// richness returns the number of the program's fields set,
+// to keep the richer one of the duplicated programs.
+func (p Prog) richness() int {
+	var n int
+	for _, f := range []string{p.ID, p.MasterID, p.To, p.Title, p.SubTitle, p.Desc, p.Pfm, p.Info, p.URL, p.ImageURL, p.Genre.ID} {
+		if f != "" {
+			n++
+		}
+	}
+	return n + len(p.Images)
+}
+
+// TimeRange returns the earliest start time and the latest end time
+// of the programs of all the stations.
+// The programs which fail Validate are skipped.
+// If there is no valid program, it returns ErrProgramNotFound.
+func (s Stations) TimeRange() (time.Time, time.Time, error) {
+	var start, end time.Time
+	for _, station := range s {
+		for _, p := range station.ValidPrograms() {
+			r, err := p.timeRange()
+			if err != nil {
+				continue
+			}
+			if start.IsZero() || r.Start.Before(start) {
+				start = r.Start
+			}
+			if end.IsZero() || r.End.After(end) {
+				end = r.End
+			}
+		}
+	}
+	if start.IsZero() {
+		return time.Time{}, time.Time{}, ErrProgramNotFound
+	}
+	return start, end, nil
+}
+
+// ProgramsByDay returns the station's programs grouped by the broadcast date
+// formatted as yyyymmdd, sorted by the start time in each day.
+// A broadcast day starts at 5:00 AM JST, so the programs
+// after midnight belong to the previous day.
+// The programs whose start time is invalid are skipped.
+func (s Station) ProgramsByDay() map[string][]Prog {
+	days := make(map[string][]Prog)
+	for _, p := range s.sortedPrograms() {
+		bd, err := p.BroadcastDate()
+		if err != nil {
+			continue
+		}
+		date := util.Date(bd)
+		days[date] = append(days[date], p)
+	}
+	return days
+}
+
+// AiringsOfTitle returns the station's programs whose Title is title,
+// sorted by the start time.
+// If no program matches exactly, the title is compared case-insensitively.
+// If there is no such program, it returns an empty slice.
+func (s Station) AiringsOfTitle(title string) []Prog {
+	progs := s.sortedPrograms()
+	airings := []Prog{}
+	for _, p := range progs {
+		if p.Title == title {
+			airings = append(airings, p)
+		}
+	}
+	if len(airings) > 0 {
+		return airings
+	}
+
+	for _, p := range progs {
+		if strings.EqualFold(p.Title, title) {
+			airings = append(airings, p)
+		}
+	}
+	return airings
+}
+
+// ValidPrograms returns the station's programs in both of the progs
+// and the scd elements, skipping the ones which fail Validate.
+// The raw programs are still in Progs and Scd.
+func (s Station) ValidPrograms() []Prog {
+	var progs []Prog
+	for _, p := range s.programs() {
+		if p.Validate() == nil {
+			progs = append(progs, p)
+		}
+	}
+	return progs
+}
+
+// Validate returns an error if Ft or To is invalid, or Ft is not before To.
+func (p Prog) Validate() error {
+	r, err := p.timeRange()
+	if err != nil {
+		return err
+	}
+	if !r.Start.Before(r.End) {
+		return fmt.Errorf("ft %s is not before to %s", p.Ft, p.To)
+	}
+	return nil
+}
+
+// CurrentProgram returns the station's program which is on the air at now.
+// If no program is on the air, it returns ErrProgramNotFound.
+func (s Station) CurrentProgram(now time.Time) (*Prog, error) {
+	for _, p := range s.programs() {
+		if p.IsOnAir(now) {
+			matched := p
+			return &matched, nil
+		}
+	}
+	return nil, ErrProgramNotFound
+}
+
+// ProgramAfter returns the station's first program which starts after t.
+// The programs do not need to be sorted.
+// If there is no such program, it returns ErrProgramNotFound.
+func (s Station) ProgramAfter(t time.Time) (*Prog, error) {
+	for _, p := range s.sortedPrograms() {
+		ft, err := p.StartTime()
+		if err != nil {
+			continue
+		}
+		if ft.After(t) {
+			matched := p
+			return &matched, nil
+		}
+	}
+	return nil, ErrProgramNotFound
+}
+
+// ProgramBefore returns the station's last program which ends by t.
+// The programs do not need to be sorted.
+// If there is no such program, it returns ErrProgramNotFound.
+func (s Station) ProgramBefore(t time.Time) (*Prog, error) {
+	progs := s.sortedPrograms()
+	for i := len(progs) - 1; i >= 0; i-- {
+		to, err := progs[i].EndTime()
+		if err != nil {
+			continue
+		}
+		if !to.After(t) {
+			matched := progs[i]
+			return &matched, nil
+		}
+	}
+	return nil, ErrProgramNotFound
+}
+
+// sortedPrograms returns the station's programs sorted by byStartTime.
+func (s Station) sortedPrograms() []Prog {
+	progs := s.programs()
+	sort.Stable(byStartTime(progs))
+	return progs
+}
+
+// Sort sorts the programs by the start time,
+// and by the end time if they start at the same time.
+// The programs whose time is invalid come last.
+// The programs in the XML are not always in order,
+// so it should be called before relying on the order.
+func (p *Progs) Sort() {
+	sort.Stable(byStartTime(p.Progs))
+}
+
+// byStartTime implements sort.Interface to sort the programs
+// by the start time and then by the end time.
+// The programs whose time is invalid come last.
+type byStartTime []Prog
+
+func (p byStartTime) Len() int      { return len(p) }
+func (p byStartTime) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+func (p byStartTime) Less(i, j int) bool {
+	if less, ok := timeLess(p[i].StartTime, p[j].StartTime); ok {
+		return less
+	}
+	less, _ := timeLess(p[i].EndTime, p[j].EndTime)
+	return less
+}
+
+// timeLess compares the times returned by a and b.
+// An invalid time is greater than any valid one.
+// ok is false if the times are equal or both invalid.
+func timeLess(a, b func() (time.Time, error)) (less, ok bool) {
+	ta, errA := a()
+	tb, errB := b()
+	switch {
+	case errA != nil && errB != nil:
+		return false, false
+	case errA != nil:
+		return false, true
+	case errB != nil:
+		return true, true
+	case ta.Equal(tb):
+		return false, false
+	}
+	return ta.Before(tb), true
+}
+
+// TimeOfDay represents a time of day in JST.
+type TimeOfDay struct {
+	Hour   int
+	Minute int
+}
+
+func (t TimeOfDay) minutes() int {
+	return t.Hour*60 + t.Minute
+}
+
+// MatchSchedule returns the programs which start on one of the days,
+// in the time of day window [from, to) in JST.
+// If from is after to, the window crosses midnight.
+// If days is empty, every day matches.
+func (s Stations) MatchSchedule(days []time.Weekday, from, to TimeOfDay) []Prog {
+	dayMatches := func(d time.Weekday) bool {
+		if len(days) == 0 {
+			return true
+		}
+		for _, day := range days {
+			if day == d {
+				return true
+			}
+		}
+		return false
+	}
+	timeMatches := func(m int) bool {
+		if from.minutes() <= to.minutes() {
+			return from.minutes() <= m && m < to.minutes()
+		}
+		return from.minutes() <= m || m < to.minutes()
+	}
+
+	var progs []Prog
+	for _, station := range s {
+		for _, p := range station.programs() {
+			ft, err := util.ParseRadikoTime(p.Ft)
+			if err != nil {
+				continue
+			}
+			if dayMatches(ft.Weekday()) && timeMatches(ft.Hour()*60+ft.Minute()) {
+				progs = append(progs, p)
+			}
+		}
+	}
+	return progs
+}
+
+// MissingMetadata returns the programs which lack any of Desc, Info or Pfm.
+func (s Stations) MissingMetadata() []ProgWithStation {
+	var progs []ProgWithStation
+	for _, station := range s {
+		for _, p := range station.programs() {
+			if strings.TrimSpace(p.Desc) != "" &&
+				strings.TrimSpace(p.Info) != "" &&
+				strings.TrimSpace(p.Pfm) != "" {
+				continue
+			}
+			progs = append(progs, ProgWithStation{
+				StationID:   station.ID,
+				StationName: station.Name,
+				Prog:        p,
+			})
+		}
+	}
+	return progs
+}
+
+// ProgPair is a pair of the programs, like the ones matched across
+// two schedules or the ones overlapping in time.
+type ProgPair struct {
+	A Prog
+	B Prog
+}
+
+// ScheduleOverlap returns the programs in a and b which have the same title
+// and start at the same time of day in JST, in the order of a.
+// Each program is matched at most once.
+func ScheduleOverlap(a, b []Prog) []ProgPair {
+	startOfDay := func(p Prog) (TimeOfDay, bool) {
+		ft, err := util.ParseRadikoTime(p.Ft)
+		if err != nil {
+			return TimeOfDay{}, false
+		}
+		return TimeOfDay{Hour: ft.Hour(), Minute: ft.Minute()}, true
+	}
+
+	used := make([]bool, len(b))
+	var pairs []ProgPair
+	for _, pa := range a {
+		ta, ok := startOfDay(pa)
+		if !ok {
+			continue
+		}
+		for j, pb := range b {
+			if used[j] || pa.Title != pb.Title {
+				continue
+			}
+			if tb, ok := startOfDay(pb); ok && ta == tb {
+				used[j] = true
+				pairs = append(pairs, ProgPair{A: pa, B: pb})
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+// Overlaps reports whether the programs are on the air at the same time.
+// The ranges are half-open, so a program ending when the other starts
+// does not overlap it. It returns false if any time is invalid.
+func (p Prog) Overlaps(other Prog) bool {
+	a, err := p.timeRange()
+	if err != nil {
+		return false
+	}
+	b, err := other.timeRange()
+	if err != nil {
+		return false
+	}
+	return a.overlaps(b)
+}
+
+// overlaps reports whether the half-open ranges intersect.
+// An empty range overlaps nothing.
+func (r TimeRange) overlaps(o TimeRange) bool {
+	return r.Start.Before(r.End) && o.Start.Before(o.End) &&
+		r.Start.Before(o.End) && o.Start.Before(r.End)
+}
+
+func (p Prog) timeRange() (TimeRange, error) {
+	ft, err := p.StartTime()
+	if err != nil {
+		return TimeRange{}, err
+	}
+	to, err := p.EndTime()
+	if err != nil {
+		return TimeRange{}, err
+	}
+	return TimeRange{Start: ft, End: to}, nil
+}
+
+// FindOverlaps returns the pairs of the programs which overlap in time.
+// The programs may be of different stations.
+// In each pair, A starts no later than B, and the pairs are sorted
+// by the start time of A.
+func FindOverlaps(progs []Prog) ([]ProgPair, error) {
+	ranges := make([]TimeRange, len(progs))
+	order := make([]int, len(progs))
+	for i, p := range progs {
+		r, err := p.timeRange()
+		if err != nil {
+			return nil, err
+		}
+		ranges[i] = r
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ranges[order[i]].Start.Before(ranges[order[j]].Start)
+	})
+
+	var pairs []ProgPair
+	for i, a := range order {
+		for _, b := range order[i+1:] {
+			if !ranges[b].Start.Before(ranges[a].End) {
+				break
+			}
+			if ranges[a].overlaps(ranges[b]) {
+				pairs = append(pairs, ProgPair{A: progs[a], B: progs[b]})
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// SlotEntry is a fixed-width time slot in the daily schedule grid.
+type SlotEntry struct {
+	Start time.Time
+	// Prog is the program which occupies the slot.
+	// It is nil if no program occupies the slot.
+	Prog *Prog
+	// Span is the number of the slots the program occupies from this slot.
+	// It is zero if the program continues from the previous slot.
+	Span int
+}
+
+// SlotGrid divides the 24 hours from dayStart into slots of the width,
+// and maps each slot to the program occupying it.
+// A slot is occupied by the program on the air at its start,
+// or the first program starting in it.
+func (s Station) SlotGrid(slot time.Duration, dayStart time.Time) []SlotEntry {
+	if slot <= 0 {
+		return nil
+	}
+
+	progs := s.programs()
+	type span struct{ ft, to time.Time }
+	spans := make([]span, len(progs))
+	for i, p := range progs {
+		ft, err := util.ParseRadikoTime(p.Ft)
+		if err != nil {
+			continue
+		}
+		to, err := util.ParseRadikoTime(p.To)
+		if err != nil {
+			continue
+		}
+		spans[i] = span{ft, to}
+	}
+
+	n := int(24 * time.Hour / slot)
+	grid := make([]SlotEntry, n)
+	for i := range grid {
+		start := dayStart.Add(time.Duration(i) * slot)
+		end := start.Add(slot)
+		grid[i].Start = start
+
+		for j, sp := range spans {
+			if !start.Before(sp.ft) && start.Before(sp.to) {
+				grid[i].Prog = &progs[j]
+				break
+			}
+		}
+		if grid[i].Prog != nil {
+			continue
+		}
+		first := -1
+		for j, sp := range spans {
+			if !sp.ft.Before(start) && sp.ft.Before(end) &&
+				(first < 0 || sp.ft.Before(spans[first].ft)) {
+				first = j
+			}
+		}
+		if first >= 0 {
+			grid[i].Prog = &progs[first]
+		}
+	}
+
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && grid[i].Prog != nil && grid[j].Prog == grid[i].Prog {
+			j++
+		}
+		if grid[i].Prog != nil {
+			grid[i].Span = j - i
+		}
+		i = j
+	}
+	return grid
+}
+
+// gridJSON is the JSON structure written by GridJSON.
+type gridJSON struct {
+	DayStart    string            `json:"day_start"`
+	SlotMinutes int               `json:"slot_minutes"`
+	Slots       []string          `json:"slots"`
+	Stations    []gridStationJSON `json:"stations"`
+	Programs    []ProgramDTO      `json:"programs"`
+}
+
+type gridStationJSON struct {
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Cells []gridCellJSON `json:"cells"`
+}
+
+// gridCellJSON is a slot of a station.
+// Program is the index in Programs, or null if no program occupies the slot.
+type gridCellJSON struct {
+	Program *int `json:"program"`
+	Span    int  `json:"span"`
+}
+
+// GridJSON writes the SlotGrid of the stations to w as a JSON.
+// It has the start times of the slots, the cells of the slots for each station,
+// and the programs referred by the index from the cells.
+// slot must be a positive whole number of minutes.
+func (s Stations) GridJSON(w io.Writer, dayStart time.Time, slot time.Duration) error {
+	if slot <= 0 {
+		return errors.New("slot must be positive")
+	}
+	if slot%time.Minute != 0 {
+		return errors.New("slot must be a whole number of minutes")
+	}
+
+	grid := gridJSON{
+		DayStart:    dayStart.Format(time.RFC3339),
+		SlotMinutes: int(slot / time.Minute),
+		Stations:    make([]gridStationJSON, 0, len(s)),
+		Programs:    []ProgramDTO{},
+	}
+	for i, n := 0, int(24*time.Hour/slot); i < n; i++ {
+		grid.Slots = append(grid.Slots, dayStart.Add(time.Duration(i)*slot).Format(time.RFC3339))
+	}
+
+	for _, station := range s {
+		indexes := make(map[*Prog]int)
+		entries := station.SlotGrid(slot, dayStart)
+		cells := make([]gridCellJSON, len(entries))
+		for i, e := range entries {
+			cells[i].Span = e.Span
+			if e.Prog == nil {
+				continue
+			}
+			index, ok := indexes[e.Prog]
+			if !ok {
+				index = len(grid.Programs)
+				indexes[e.Prog] = index
+				grid.Programs = append(grid.Programs, e.Prog.ToDTO())
+			}
+			cells[i].Program = &index
+		}
+		grid.Stations = append(grid.Stations, gridStationJSON{
+			ID:    station.ID,
+			Name:  station.Name,
+			Cells: cells,
+		})
+	}
+
+	return json.NewEncoder(w).Encode(grid)
+}
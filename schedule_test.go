@@ -0,0 +1,588 @@
+package radiko
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+func TestStations_StartingWithin(t *testing.T) {
+	now, err := util.ParseRadikoTime("20161112200000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stations := Stations{
+		{
+			ID: "TBS",
+			Progs: Progs{Progs: []Prog{
+				{Ft: "20161112201000", Title: "+10m"},
+				{Ft: "20161112213000", Title: "+90m"},
+			}},
+		},
+		{
+			ID: "LFR",
+			Scd: Scd{Progs: Progs{Progs: []Prog{
+				{Ft: "20161112200000", Title: "now"},
+				{Ft: "20161112205000", Title: "+50m"},
+			}}},
+		},
+	}
+
+	progs := stations.StartingWithin(now, time.Hour)
+	expected := []struct {
+		stationID string
+		title     string
+	}{
+		{"TBS", "+10m"},
+		{"LFR", "+50m"},
+	}
+	if len(progs) != len(expected) {
+		t.Fatalf("expected %d programs, but %d", len(expected), len(progs))
+	}
+	for i, e := range expected {
+		if progs[i].StationID != e.stationID || progs[i].Prog.Title != e.title {
+			t.Errorf("expected %s %s, but %s %s",
+				e.stationID, e.title, progs[i].StationID, progs[i].Prog.Title)
+		}
+	}
+}
+
+func TestStation_CurrentProgram(t *testing.T) {
+	s := Station{
+		ID: "TBS",
+		Progs: Progs{Progs: []Prog{
+			{Ft: "20161112220000", To: "20161112233000", Title: "night"},
+			// There is a gap from 23:30 to 24:00.
+			{Ft: "20161112240000", To: "20161112260000", Title: "midnight"},
+		}},
+	}
+
+	cases := []struct {
+		now      string
+		expected string
+	}{
+		{"20161112220000", "night"},
+		{"20161113003000", "midnight"},
+		{"20161112234500", ""},
+		{"20161113020000", ""},
+	}
+	for _, c := range cases {
+		now, err := util.ParseRadikoTime(c.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prog, err := s.CurrentProgram(now)
+		if c.expected == "" {
+			if err != ErrProgramNotFound {
+				t.Errorf("%s: expected %s, but %v", c.now, ErrProgramNotFound, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prog.Title != c.expected {
+			t.Errorf("%s: expected %s, but %s", c.now, c.expected, prog.Title)
+		}
+	}
+}
+
+func TestStations_GridAndTimeRange(t *testing.T) {
+	s := Stations{
+		{ID: "TBS", Progs: Progs{Progs: []Prog{
+			{Ft: "20161112220000", To: "20161113000000", Title: "night"},
+			{Ft: "20161112200000", To: "20161112220000", Title: "evening"},
+		}}},
+		{ID: "LFR", Progs: Progs{Progs: []Prog{
+			{Ft: "20161112250000", To: "20161112270000", Title: "ann"},
+			{Ft: "invalid", Title: "invalid"},
+		}}},
+	}
+
+	grid := s.Grid()
+	for id, expected := range map[string]string{
+		"TBS": "evening night",
+		"LFR": "ann invalid",
+	} {
+		var titles []string
+		for _, p := range grid[id] {
+			titles = append(titles, p.Title)
+		}
+		if actual := strings.Join(titles, " "); expected != actual {
+			t.Errorf("%s: expected %s, but %s", id, expected, actual)
+		}
+	}
+
+	start, end, err := s.TimeRange()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "20161112200000"; expected != util.Datetime(start) {
+		t.Errorf("expected %s, but %s", expected, util.Datetime(start))
+	}
+	if expected := "20161113030000"; expected != util.Datetime(end) {
+		t.Errorf("expected %s, but %s", expected, util.Datetime(end))
+	}
+
+	if _, _, err := (Stations{}).TimeRange(); err != ErrProgramNotFound {
+		t.Errorf("expected %s, but %v", ErrProgramNotFound, err)
+	}
+}
+
+func TestStations_MergeDedup(t *testing.T) {
+	day1 := Stations{
+		{ID: "TBS", Name: "TBSラジオ", Progs: Progs{Progs: []Prog{
+			{Ft: "20161112220000", To: "20161113000000", Title: "night"},
+		}}},
+	}
+	day2 := Stations{
+		{ID: "LFR", Progs: Progs{Progs: []Prog{
+			{Ft: "20161112250000", To: "20161112270000", Title: "ann"},
+		}}},
+		{ID: "TBS", Progs: Progs{Progs: []Prog{
+			{Ft: "20161112220000", To: "20161113000000", Title: "night", Pfm: "宇多丸"},
+			{Ft: "20161112200000", To: "20161112220000", Title: "evening"},
+		}}, Scd: Scd{Progs: Progs{Progs: []Prog{
+			{Ft: "20161112200000", Title: "evening"},
+		}}}},
+	}
+
+	merged := day1.Merge(day2)
+	if expected, actual := "TBS,LFR", strings.Join(merged.IDs(), ","); expected != actual {
+		t.Fatalf("expected %s, but %s", expected, actual)
+	}
+	if expected := "TBSラジオ"; merged[0].Name != expected {
+		t.Errorf("expected %s, but %s", expected, merged[0].Name)
+	}
+	var titles []string
+	for _, p := range merged[0].Progs.Progs {
+		titles = append(titles, p.Title)
+	}
+	if expected, actual := "evening night", strings.Join(titles, " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+	if expected := "宇多丸"; merged[0].Progs.Progs[1].Pfm != expected {
+		t.Errorf("expected the richer program, but %v", merged[0].Progs.Progs[1])
+	}
+	if expected := "20161112220000"; merged[0].Progs.Progs[0].To != expected {
+		t.Errorf("expected the richer program, but %v", merged[0].Progs.Progs[0])
+	}
+	if len(merged[0].Scd.Progs.Progs) != 0 {
+		t.Errorf("expected no scd, but %v", merged[0].Scd.Progs.Progs)
+	}
+
+	// The inputs are not modified.
+	if len(day1[0].Progs.Progs) != 1 || day1[0].Progs.Progs[0].Pfm != "" {
+		t.Errorf("the input is modified: %v", day1[0].Progs.Progs)
+	}
+
+	if dedup := append(day2, day2...).Dedup(); len(dedup) != 2 || len(dedup[1].Progs.Progs) != 2 {
+		t.Errorf("unexpected stations: %v", dedup)
+	}
+}
+
+func TestStation_AiringsOfTitle(t *testing.T) {
+	s := Station{ID: "LFR", Progs: Progs{Progs: []Prog{
+		{Ft: "20161114010000", Title: "All Night Nippon"},
+		{Ft: "20161113010000", Title: "all night nippon"},
+		{Ft: "20161112010000", Title: "ALL NIGHT NIPPON"},
+		{Ft: "20161112230000", Title: "news"},
+	}}}
+
+	cases := []struct {
+		title    string
+		expected string
+	}{
+		{"All Night Nippon", "20161114010000"},
+		{"all NIGHT nippon", "20161112010000 20161113010000 20161114010000"},
+		{"music", ""},
+	}
+	for _, c := range cases {
+		airings := s.AiringsOfTitle(c.title)
+		if airings == nil {
+			t.Errorf("%s: expected an empty slice, but nil", c.title)
+		}
+		var fts []string
+		for _, p := range airings {
+			fts = append(fts, p.Ft)
+		}
+		if actual := strings.Join(fts, " "); c.expected != actual {
+			t.Errorf("%s: expected %s, but %s", c.title, c.expected, actual)
+		}
+	}
+}
+
+func TestStation_ProgramsByDay(t *testing.T) {
+	s := Station{
+		ID: "TBS",
+		Progs: Progs{Progs: []Prog{
+			{Ft: "20161113010000", To: "20161113020000", Title: "late night"},
+			{Ft: "20161112220000", To: "20161112240000", Title: "night"},
+			{Ft: "20161113050000", To: "20161113060000", Title: "morning"},
+			{Ft: "invalid", Title: "invalid"},
+		}},
+	}
+
+	days := s.ProgramsByDay()
+	if expected := 2; len(days) != expected {
+		t.Errorf("expected %d days, but %d", expected, len(days))
+	}
+	for date, expected := range map[string]string{
+		"20161112": "night late night",
+		"20161113": "morning",
+	} {
+		var titles []string
+		for _, p := range days[date] {
+			titles = append(titles, p.Title)
+		}
+		if actual := strings.Join(titles, " "); expected != actual {
+			t.Errorf("%s: expected %s, but %s", date, expected, actual)
+		}
+	}
+}
+
+func TestStation_ValidPrograms(t *testing.T) {
+	s := Station{
+		ID: "TBS",
+		Progs: Progs{Progs: []Prog{
+			{Ft: "20161112200000", To: "20161112220000", Title: "evening"},
+			{Ft: "2016111222", To: "20161112233000", Title: "broken ft"},
+			{Ft: "20161112233000", To: "invalid", Title: "broken to"},
+			{Ft: "20161112240000", To: "20161112240000", Title: "empty"},
+		}},
+		Scd: Scd{Progs: Progs{Progs: []Prog{
+			{Ft: "20161112240000", To: "20161112260000", Title: "midnight"},
+		}}},
+	}
+
+	var titles []string
+	for _, p := range s.ValidPrograms() {
+		titles = append(titles, p.Title)
+	}
+	if expected, actual := "evening midnight", strings.Join(titles, " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+	if expected := 4; len(s.Progs.Progs) != expected {
+		t.Errorf("expected %d raw programs, but %d", expected, len(s.Progs.Progs))
+	}
+
+	if err := s.Progs.Progs[1].Validate(); err == nil {
+		t.Error("Should detect an error.")
+	}
+	if err := s.Progs.Progs[3].Validate(); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestStation_ProgramAfterBefore(t *testing.T) {
+	s := Station{
+		ID: "TBS",
+		Progs: Progs{Progs: []Prog{
+			{Ft: "20161112240000", To: "20161112260000", Title: "midnight"},
+			{Ft: "20161112220000", To: "20161112233000", Title: "night"},
+			{Ft: "20161112200000", To: "20161112220000", Title: "evening"},
+		}},
+	}
+
+	now, err := util.ParseRadikoTime("20161112223000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := s.ProgramAfter(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "midnight"; expected != next.Title {
+		t.Errorf("expected %s, but %s", expected, next.Title)
+	}
+	prev, err := s.ProgramBefore(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "evening"; expected != prev.Title {
+		t.Errorf("expected %s, but %s", expected, prev.Title)
+	}
+
+	first, err := util.ParseRadikoTime("20161112203000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ProgramBefore(first); err != ErrProgramNotFound {
+		t.Errorf("expected %s, but %v", ErrProgramNotFound, err)
+	}
+	last, err := util.ParseRadikoTime("20161113003000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ProgramAfter(last); err != ErrProgramNotFound {
+		t.Errorf("expected %s, but %v", ErrProgramNotFound, err)
+	}
+
+	if expected := "midnight"; s.Progs.Progs[0].Title != expected {
+		t.Errorf("the programs should not be sorted in place: %s", s.Progs.Progs[0].Title)
+	}
+}
+
+func TestProgs_Sort(t *testing.T) {
+	p := Progs{Progs: []Prog{
+		{Ft: "invalid", Title: "invalid"},
+		{Ft: "20161112240000", To: "20161112260000", Title: "midnight"},
+		{Ft: "20161112220000", To: "20161112233000", Title: "night-long"},
+		{Ft: "20161112200000", To: "20161112220000", Title: "evening"},
+		{Ft: "20161112220000", To: "20161112230000", Title: "night-short"},
+	}}
+	p.Sort()
+
+	var titles []string
+	for _, prog := range p.Progs {
+		titles = append(titles, prog.Title)
+	}
+	expected := "evening night-short night-long midnight invalid"
+	if actual := strings.Join(titles, " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+}
+
+func TestFindOverlaps(t *testing.T) {
+	progs := []Prog{
+		{Ft: "20161112220000", To: "20161113000000", Title: "TBS night"},
+		{Ft: "20161112200000", To: "20161112220000", Title: "TBS evening"},
+		{Ft: "20161112230000", To: "20161112233000", Title: "LFR night"},
+		{Ft: "20161112233000", To: "20161112250000", Title: "QRR midnight"},
+		{Ft: "20161112210000", To: "20161112210000", Title: "empty"},
+	}
+
+	pairs, err := FindOverlaps(progs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, p := range pairs {
+		actual = append(actual, p.A.Title+" & "+p.B.Title)
+	}
+	expected := "TBS night & LFR night, TBS night & QRR midnight"
+	if strings.Join(actual, ", ") != expected {
+		t.Errorf("expected %s, but %s", expected, strings.Join(actual, ", "))
+	}
+
+	if progs[2].Overlaps(progs[3]) {
+		t.Error("Adjacent programs should not overlap.")
+	}
+	if !progs[3].Overlaps(progs[0]) {
+		t.Error("Programs across midnight should overlap.")
+	}
+
+	if _, err := FindOverlaps([]Prog{{Ft: "invalid"}}); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestStations_MatchSchedule(t *testing.T) {
+	var progs []Prog
+	// 2016-11-14 is Monday.
+	for day := 14; day <= 20; day++ {
+		for _, hm := range []string{"0600", "0700", "0830", "0900"} {
+			progs = append(progs, Prog{Ft: fmt.Sprintf("201611%02d%s00", day, hm)})
+		}
+	}
+	stations := Stations{{ID: "TBS", Progs: Progs{Progs: progs}}}
+
+	weekdays := []time.Weekday{
+		time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday,
+	}
+	matched := stations.MatchSchedule(weekdays, TimeOfDay{Hour: 7}, TimeOfDay{Hour: 9})
+	if expected := 10; len(matched) != expected {
+		t.Fatalf("expected %d programs, but %d", expected, len(matched))
+	}
+	for _, p := range matched {
+		hm := p.Ft[8:12]
+		if hm != "0700" && hm != "0830" {
+			t.Errorf("unexpected program: %s", p.Ft)
+		}
+		if day := p.Ft[6:8]; day == "19" || day == "20" {
+			t.Errorf("unexpected weekend program: %s", p.Ft)
+		}
+	}
+
+	// crossing midnight
+	matched = stations.MatchSchedule(nil, TimeOfDay{Hour: 23}, TimeOfDay{Hour: 6, Minute: 30})
+	if expected := 7; len(matched) != expected {
+		t.Errorf("expected %d programs, but %d", expected, len(matched))
+	}
+}
+
+func TestStations_MissingMetadata(t *testing.T) {
+	stations := Stations{
+		{
+			ID: "TBS",
+			Progs: Progs{Progs: []Prog{
+				{Ft: "20161112200000", Desc: "desc", Info: "info", Pfm: "pfm"},
+				{Ft: "20161112220000", Desc: " ", Info: "info", Pfm: "pfm"},
+			}},
+		},
+		{
+			ID: "LFR",
+			Progs: Progs{Progs: []Prog{
+				{Ft: "20161112230000", Desc: "desc", Info: "info", Pfm: "pfm"},
+			}},
+		},
+	}
+
+	progs := stations.MissingMetadata()
+	if len(progs) != 1 {
+		t.Fatalf("expected 1 program, but %d", len(progs))
+	}
+	if progs[0].StationID != "TBS" || progs[0].Prog.Ft != "20161112220000" {
+		t.Errorf("unexpected program: %s %s", progs[0].StationID, progs[0].Prog.Ft)
+	}
+}
+
+func TestStation_SlotGrid(t *testing.T) {
+	dayStart, err := util.ParseRadikoTime("20161112050000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	station := Station{
+		ID: "TBS",
+		Progs: Progs{Progs: []Prog{
+			{Ft: "20161112050000", To: "20161112053000", Title: "30m"},
+			{Ft: "20161112053000", To: "20161112070000", Title: "90m"},
+			{Ft: "20161112071000", To: "20161112080000", Title: "50m"},
+			{Ft: "20161113040000", To: "20161113050000", Title: "last"},
+		}},
+	}
+
+	grid := station.SlotGrid(30*time.Minute, dayStart)
+	if expected := 48; len(grid) != expected {
+		t.Fatalf("expected %d slots, but %d", expected, len(grid))
+	}
+
+	cases := []struct {
+		slot  int
+		title string
+		span  int
+	}{
+		{0, "30m", 1},
+		{1, "90m", 3},
+		{2, "90m", 0},
+		{3, "90m", 0},
+		{4, "50m", 2},
+		{5, "50m", 0},
+		{6, "", 0},
+		{46, "last", 2},
+		{47, "last", 0},
+	}
+	for _, c := range cases {
+		e := grid[c.slot]
+		var title string
+		if e.Prog != nil {
+			title = e.Prog.Title
+		}
+		if c.title != title || c.span != e.Span {
+			t.Errorf("slot %d: expected %q span %d, but %q span %d", c.slot, c.title, c.span, title, e.Span)
+		}
+		if expected := dayStart.Add(time.Duration(c.slot) * 30 * time.Minute); !expected.Equal(e.Start) {
+			t.Errorf("slot %d: expected %s, but %s", c.slot, expected, e.Start)
+		}
+	}
+}
+
+func TestScheduleOverlap(t *testing.T) {
+	a := []Prog{
+		{Ft: "20161114070000", To: "20161114083000", Title: "森本毅郎・スタンバイ!"},
+		{Ft: "20161114083000", To: "20161114130000", Title: "ジェーン・スー 生活は踊る"},
+		{Ft: "20161114220000", To: "20161114230000", Title: "月曜日の特番"},
+	}
+	b := []Prog{
+		{Ft: "20161115063000", To: "20161115070000", Title: "朝のニュース"},
+		{Ft: "20161115070000", To: "20161115083000", Title: "森本毅郎・スタンバイ!"},
+		{Ft: "20161115090000", To: "20161115130000", Title: "ジェーン・スー 生活は踊る"},
+		{Ft: "20161115220000", To: "20161115230000", Title: "火曜日の特番"},
+	}
+
+	pairs := ScheduleOverlap(a, b)
+	if expected := 1; len(pairs) != expected {
+		t.Fatalf("expected %d pairs, but %d", expected, len(pairs))
+	}
+	if pairs[0].A.Ft != a[0].Ft || pairs[0].B.Ft != b[1].Ft {
+		t.Errorf("expected %s and %s, but %s and %s", a[0].Ft, b[1].Ft, pairs[0].A.Ft, pairs[0].B.Ft)
+	}
+}
+
+func TestStations_GridJSON(t *testing.T) {
+	dayStart, err := util.ParseRadikoTime("20161112050000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stations := Stations{
+		{ID: "TBS", Name: "TBSラジオ", Progs: Progs{Progs: []Prog{
+			{Ft: "20161112050000", To: "20161112070000", Title: "TBS morning"},
+		}}},
+		{ID: "QRR", Name: "文化放送", Progs: Progs{Progs: []Prog{
+			{Ft: "20161112050000", To: "20161112060000", Title: "QRR morning"},
+			{Ft: "20161112060000", To: "20161112070000", Title: "QRR news"},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	if err = stations.GridJSON(&buf, dayStart, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	var grid struct {
+		Slots    []string `json:"slots"`
+		Stations []struct {
+			ID    string `json:"id"`
+			Cells []struct {
+				Program *int `json:"program"`
+				Span    int  `json:"span"`
+			} `json:"cells"`
+		} `json:"stations"`
+		Programs []ProgramDTO `json:"programs"`
+	}
+	if err = json.Unmarshal(buf.Bytes(), &grid); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := 24; len(grid.Slots) != expected {
+		t.Errorf("expected %d slots, but %d", expected, len(grid.Slots))
+	}
+	if expected := "2016-11-12T05:00:00+09:00"; grid.Slots[0] != expected {
+		t.Errorf("expected %s, but %s", expected, grid.Slots[0])
+	}
+	if expected := 2; len(grid.Stations) != expected {
+		t.Fatalf("expected %d stations, but %d", expected, len(grid.Stations))
+	}
+	for _, s := range grid.Stations {
+		if expected := 24; len(s.Cells) != expected {
+			t.Errorf("%s: expected %d cells, but %d", s.ID, expected, len(s.Cells))
+		}
+	}
+	if expected := 3; len(grid.Programs) != expected {
+		t.Fatalf("expected %d programs, but %d", expected, len(grid.Programs))
+	}
+
+	tbs := grid.Stations[0].Cells
+	if tbs[0].Program == nil || grid.Programs[*tbs[0].Program].Title != "TBS morning" || tbs[0].Span != 2 {
+		t.Errorf("unexpected first cell of TBS: %+v", tbs[0])
+	}
+	if tbs[2].Program != nil {
+		t.Errorf("expected an empty cell, but %d", *tbs[2].Program)
+	}
+	qrr := grid.Stations[1].Cells
+	if qrr[1].Program == nil || grid.Programs[*qrr[1].Program].Title != "QRR news" {
+		t.Errorf("unexpected second cell of QRR: %+v", qrr[1])
+	}
+
+	for _, slot := range []time.Duration{0, 90 * time.Second} {
+		if err = stations.GridJSON(ioutil.Discard, dayStart, slot); err == nil {
+			t.Errorf("%s: Should detect an error.", slot)
+		}
+	}
+}
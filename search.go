@@ -0,0 +1,206 @@
+package radiko
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+// SearchWeeklyPrograms returns the station's programs of the week
+// whose Title, SubTitle, Pfm or Desc contains the keyword, ignoring case.
+// If the keyword is empty, all the programs are returned.
+// This API wraps GetWeeklyPrograms.
+func (c *Client) SearchWeeklyPrograms(ctx context.Context, stationID, keyword string) ([]Prog, error) {
+	keyword = strings.ToLower(keyword)
+	return c.searchWeeklyPrograms(ctx, stationID, func(s string) bool {
+		return strings.Contains(strings.ToLower(s), keyword)
+	})
+}
+
+// SearchWeeklyProgramsRegexp is like SearchWeeklyPrograms,
+// but matches the fields with re.
+// If re is nil, all the programs are returned.
+func (c *Client) SearchWeeklyProgramsRegexp(ctx context.Context, stationID string, re *regexp.Regexp) ([]Prog, error) {
+	if re == nil {
+		return c.SearchWeeklyPrograms(ctx, stationID, "")
+	}
+	return c.searchWeeklyPrograms(ctx, stationID, re.MatchString)
+}
+
+func (c *Client) searchWeeklyPrograms(ctx context.Context, stationID string, match func(string) bool) ([]Prog, error) {
+	stations, err := c.GetWeeklyPrograms(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var progs []Prog
+	for _, s := range stations {
+		if s.ID != stationID {
+			continue
+		}
+		for _, p := range s.Progs.Progs {
+			for _, field := range []string{p.Title, p.SubTitle, p.Pfm, p.Desc} {
+				if match(field) {
+					progs = append(progs, p)
+					break
+				}
+			}
+		}
+	}
+	return progs, nil
+}
+
+const (
+	searchDayLayout  = "2006-01-02"
+	searchTimeLayout = "2006-01-02 15:04:05"
+)
+
+// SearchQuery is the query of SearchPrograms.
+// The empty fields are not used as filters.
+type SearchQuery struct {
+	// Key is the keyword.
+	Key string
+	// AreaID filters the programs which are broadcast in the area.
+	AreaID string
+	// RegionID filters the programs of the region, e.g. "kanto".
+	RegionID string
+	// CulAreaID filters the programs of the cultural area.
+	CulAreaID string
+	// StartDay and EndDay filter the broadcast dates.
+	StartDay time.Time
+	EndDay   time.Time
+	// PageIdx is the index of the page from 0.
+	PageIdx int
+	// RowLimit is the number of the programs in a page.
+	// If it is not positive, radiko's default is used.
+	RowLimit int
+}
+
+// SearchResult is the result of SearchPrograms.
+type SearchResult struct {
+	Programs []ProgWithStation
+	// Total is the number of all the matched programs.
+	Total    int
+	PageIdx  int
+	RowLimit int
+}
+
+// SearchPrograms searches the programs with radiko's search API.
+func (c *Client) SearchPrograms(ctx context.Context, q SearchQuery) (SearchResult, error) {
+	query := map[string]string{
+		"key":       q.Key,
+		"page_idx":  strconv.Itoa(q.PageIdx),
+		"app_id":    "pc",
+		"action_id": "0",
+	}
+	if q.AreaID != "" {
+		query["area_id"] = q.AreaID
+	}
+	if q.RegionID != "" {
+		query["region_id"] = q.RegionID
+	}
+	if q.CulAreaID != "" {
+		query["cul_area_id"] = q.CulAreaID
+	}
+	if !q.StartDay.IsZero() {
+		query["start_day"] = q.StartDay.In(util.Location()).Format(searchDayLayout)
+	}
+	if !q.EndDay.IsZero() {
+		query["end_day"] = q.EndDay.In(util.Location()).Format(searchDayLayout)
+	}
+	if q.RowLimit > 0 {
+		query["row_limit"] = strconv.Itoa(q.RowLimit)
+	}
+
+	req, err := c.newRequest(ctx, "GET", apiPath(apiV3, "program/search"), &Params{
+		query: query,
+	})
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	if resp.StatusCode != 200 {
+		return SearchResult{}, newAPIError(resp.StatusCode, b)
+	}
+
+	var d searchData
+	if err = json.Unmarshal(b, &d); err != nil {
+		return SearchResult{}, err
+	}
+	return d.result()
+}
+
+// searchData includes a response struct for client's users.
+type searchData struct {
+	Meta struct {
+		PageIdx     int `json:"page_idx"`
+		RowLimit    int `json:"row_limit"`
+		ResultCount int `json:"result_count"`
+	} `json:"meta"`
+	Data []struct {
+		StationID   string `json:"station_id"`
+		ProgramID   string `json:"id"`
+		StartTime   string `json:"start_time"`
+		EndTime     string `json:"end_time"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Info        string `json:"info"`
+		Performer   string `json:"performer"`
+		ProgramURL  string `json:"program_url"`
+		Img         string `json:"img"`
+	} `json:"data"`
+}
+
+func (d *searchData) result() (SearchResult, error) {
+	result := SearchResult{
+		Total:    d.Meta.ResultCount,
+		PageIdx:  d.Meta.PageIdx,
+		RowLimit: d.Meta.RowLimit,
+	}
+	for _, v := range d.Data {
+		ft, err := time.ParseInLocation(searchTimeLayout, v.StartTime, util.Location())
+		if err != nil {
+			return SearchResult{}, err
+		}
+		to, err := time.ParseInLocation(searchTimeLayout, v.EndTime, util.Location())
+		if err != nil {
+			return SearchResult{}, err
+		}
+
+		p := Prog{
+			ID:    v.ProgramID,
+			Ft:    util.Datetime(ft),
+			To:    util.Datetime(to),
+			Dur:   strconv.Itoa(int(to.Sub(ft) / time.Second)),
+			Title: v.Title,
+			Desc:  v.Description,
+			Info:  v.Info,
+			Pfm:   v.Performer,
+			URL:   v.ProgramURL,
+		}
+		if isImageURL(v.Img) {
+			p.Images = Images{"img": v.Img}
+		}
+		result.Programs = append(result.Programs, ProgWithStation{
+			StationID: v.StationID,
+			Prog:      p,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,121 @@
+package radiko
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+func TestSearchWeeklyPrograms(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("weekly_search.xml"))
+	defer closer()
+
+	cases := []struct {
+		keyword  string
+		expected []string
+	}{
+		{"news", []string{"40001", "40002"}},
+		{"爆笑問題", []string{"40003"}},
+		{"チキ", []string{"40002"}},
+		{"", []string{"40001", "40002", "40003"}},
+		{"not found", nil},
+	}
+	for _, cs := range cases {
+		progs, err := c.SearchWeeklyPrograms(context.Background(), "TBS", cs.keyword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(progs) != len(cs.expected) {
+			t.Errorf("%q: expected %d programs, but %d", cs.keyword, len(cs.expected), len(progs))
+			continue
+		}
+		for i, id := range cs.expected {
+			if progs[i].ID != id {
+				t.Errorf("%q: expected %s, but %s", cs.keyword, id, progs[i].ID)
+			}
+		}
+	}
+}
+
+func TestSearchWeeklyProgramsRegexp(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("weekly_search.xml"))
+	defer closer()
+
+	progs, err := c.SearchWeeklyProgramsRegexp(context.Background(), "TBS", regexp.MustCompile(`^Session-\d+$`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progs) != 1 || progs[0].ID != "40002" {
+		t.Errorf("expected [40002], but %v", progs)
+	}
+}
+
+func TestSearchPrograms(t *testing.T) {
+	var query url.Values
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/api/program/search" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query()
+		http.ServeFile(w, r, filepath.Join(testdataDir, "search.json"))
+	}))
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161110050000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.SearchPrograms(context.Background(), SearchQuery{
+		Key:      "爆笑問題",
+		AreaID:   "JP13",
+		StartDay: start,
+		EndDay:   start.AddDate(0, 0, 6),
+		RowLimit: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedQuery := map[string]string{
+		"key":       "爆笑問題",
+		"area_id":   "JP13",
+		"start_day": "2016-11-10",
+		"end_day":   "2016-11-16",
+		"page_idx":  "0",
+		"row_limit": "2",
+	}
+	for k, v := range expectedQuery {
+		if actual := query.Get(k); v != actual {
+			t.Errorf("%s: expected %s, but %s", k, v, actual)
+		}
+	}
+	if _, ok := query["region_id"]; ok {
+		t.Error("region_id should not be set.")
+	}
+
+	if result.Total != 5 || result.RowLimit != 2 {
+		t.Errorf("unexpected meta: %+v", result)
+	}
+	if expected := 2; len(result.Programs) != expected {
+		t.Fatalf("expected %d programs, but %d", expected, len(result.Programs))
+	}
+	p := result.Programs[0]
+	if p.StationID != "TBS" || p.Prog.ID != "40003" {
+		t.Errorf("unexpected program: %+v", p)
+	}
+	if expected := "20161115010000"; expected != p.Prog.Ft {
+		t.Errorf("expected %s, but %s", expected, p.Prog.Ft)
+	}
+	if expected := "7200"; expected != p.Prog.Dur {
+		t.Errorf("expected %s, but %s", expected, p.Prog.Dur)
+	}
+	if _, ok := p.Prog.Image("img"); !ok {
+		t.Error("expected an image.")
+	}
+}
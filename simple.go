@@ -0,0 +1,67 @@
+package radiko
+
+import (
+	"context"
+	"time"
+)
+
+// defaultSimpleTimeout is the default timeout of each call of SimpleClient.
+const defaultSimpleTimeout = 30 * time.Second
+
+// SimpleClient wraps Client for quick scripts which do not need contexts.
+// Its methods call the methods of Client with a context
+// which times out after Timeout.
+// The methods of Client are still available through the embedded Client.
+type SimpleClient struct {
+	*Client
+	// Timeout is the timeout of each call.
+	// If it is not positive, the calls never time out.
+	Timeout time.Duration
+}
+
+// NewSimpleClient returns a SimpleClient wrapping c with the default timeout.
+func NewSimpleClient(c *Client) *SimpleClient {
+	return &SimpleClient{Client: c, Timeout: defaultSimpleTimeout}
+}
+
+func (s *SimpleClient) newContext() (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), s.Timeout)
+}
+
+// Stations calls GetStations.
+func (s *SimpleClient) Stations(date time.Time) (Stations, error) {
+	ctx, cancel := s.newContext()
+	defer cancel()
+	return s.GetStations(ctx, date)
+}
+
+// NowPrograms calls GetNowPrograms.
+func (s *SimpleClient) NowPrograms() (Stations, error) {
+	ctx, cancel := s.newContext()
+	defer cancel()
+	return s.GetNowPrograms(ctx)
+}
+
+// WeeklyPrograms calls GetWeeklyPrograms.
+func (s *SimpleClient) WeeklyPrograms(stationID string) (Stations, error) {
+	ctx, cancel := s.newContext()
+	defer cancel()
+	return s.GetWeeklyPrograms(ctx, stationID)
+}
+
+// RadioStations calls GetRadioStations.
+func (s *SimpleClient) RadioStations() (RadioStations, error) {
+	ctx, cancel := s.newContext()
+	defer cancel()
+	return s.GetRadioStations(ctx)
+}
+
+// TimeshiftPlaylist calls TimeshiftPlaylistM3U8.
+func (s *SimpleClient) TimeshiftPlaylist(stationID string, start time.Time) (string, error) {
+	ctx, cancel := s.newContext()
+	defer cancel()
+	return s.TimeshiftPlaylistM3U8(ctx, stationID, start)
+}
@@ -0,0 +1,41 @@
+package radiko
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSimpleClient(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("weekly.xml"))
+	defer closer()
+
+	s := NewSimpleClient(c)
+	stations, err := s.WeeklyPrograms("TBS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "TBS", stations.IDs(); len(actual) != 1 || expected != actual[0] {
+		t.Errorf("expected %s, but %v", expected, actual)
+	}
+}
+
+func TestSimpleClient_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-done:
+		}
+	}))
+	defer closer()
+	defer close(done)
+
+	s := NewSimpleClient(c)
+	s.Timeout = 50 * time.Millisecond
+	if _, err := s.Stations(time.Now()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %s, but %v", context.DeadlineExceeded, err)
+	}
+}
@@ -1,13 +1,22 @@
 package radiko
 
 import (
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"path"
+
+	"github.com/chikulla/go-radiko/internal/m3u8"
 )
 
+// liveStreamURL is the format of the live playlist url.
+var liveStreamURL = "https://f-radiko.smartstream.ne.jp/%s/_definst_/simul-stream.stream/playlist.m3u8"
+
 // URLItem represents a stream url.
 type URLItem struct {
 	Areafree bool   `xml:"areafree,attr"`
@@ -37,6 +46,27 @@ func GetStreamMultiURL(stationID string) ([]URLItem, error) {
 	return urlData.Items, err
 }
 
+// GetStreamMultiURL is like the package level GetStreamMultiURL,
+// but sends the request to the Client's URL set by WithBaseURL.
+func (c *Client) GetStreamMultiURL(ctx context.Context, stationID string) ([]URLItem, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return nil, err
+	}
+
+	apiEndpoint := path.Join(apiV2, "station/stream_multi",
+		fmt.Sprintf("%s.xml", stationID))
+
+	var urlData streamURLData
+	err := c.getXML(ctx, apiEndpoint, &Params{}, func(r io.Reader) error {
+		urlData = streamURLData{}
+		return xml.NewDecoder(r).Decode(&urlData)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return urlData.Items, nil
+}
+
 type streamURLData struct {
 	XMLName xml.Name  `xml:"url"`
 	Items   []URLItem `xml:"item"`
@@ -47,3 +77,58 @@ func GetLiveURL(stationID string) string {
 	endpoint := path.Join("#!/live", stationID)
 	return defaultEndpoint + "/" + endpoint
 }
+
+// StreamPlaylistM3U8 returns the uri of the station's live stream.
+// If the station is not available in the Client's area,
+// it returns ErrAreaRestricted.
+func (c *Client) StreamPlaylistM3U8(ctx context.Context, stationID string) (string, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return "", err
+	}
+
+	station, err := c.GetStationByID(ctx, stationID)
+	if err != nil {
+		return "", err
+	}
+	if !station.AvailableInArea(c.AreaID()) {
+		return "", ErrAreaRestricted
+	}
+
+	playlistURL := fmt.Sprintf(liveStreamURL, stationID)
+	if c.listedStreamURLs {
+		// The token is only sent to the host of the default live stream.
+		if def, err := url.Parse(playlistURL); err == nil {
+			if u, ok := urlOnHost(station.playlistCreateURL(false), def.Host); ok {
+				playlistURL = u.String()
+			}
+		}
+	}
+
+	req, err := http.NewRequest("GET", playlistURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", c.getUserAgent())
+	req.Header.Set(radikoAuthTokenHeader, c.AuthToken())
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", readAPIError(resp)
+	}
+
+	uri, err := m3u8.GetURI(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	// GetURI returns the empty uri for a media playlist.
+	if uri == "" {
+		return "", errors.New("uri is not found in the playlist")
+	}
+	return uri, nil
+}
@@ -1,6 +1,9 @@
 package radiko
 
 import (
+	"context"
+	"net/http"
+	"path/filepath"
 	"testing"
 )
 
@@ -23,6 +26,33 @@ func TestGetStreamMultiURL_EmptyStationID(t *testing.T) {
 	}
 }
 
+func TestClient_GetStreamMultiURL(t *testing.T) {
+	var requested string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		w.Write([]byte(`<url><item areafree="0">https://f-radiko.smartstream.ne.jp/LFR/_definst_/simul-stream.stream/playlist.m3u8</item><item areafree="1">https://c-radiko.smartstream.ne.jp/LFR/_definst_/simul-stream.stream/playlist.m3u8</item></url>`))
+	}))
+	defer closer()
+
+	items, err := c.GetStreamMultiURL(context.Background(), "LFR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/v2/station/stream_multi/LFR.xml"; expected != requested {
+		t.Errorf("expected %s, but %s", expected, requested)
+	}
+	if expected := 2; len(items) != expected {
+		t.Fatalf("expected %d, but %d", expected, len(items))
+	}
+	if !items[1].Areafree {
+		t.Error("expected areafree item")
+	}
+
+	if _, err := c.GetStreamMultiURL(context.Background(), ""); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
 func TestGetLiveURL(t *testing.T) {
 	stationID := "LFR"
 	url := GetLiveURL(stationID)
@@ -30,3 +60,53 @@ func TestGetLiveURL(t *testing.T) {
 		t.Error("A live url is empty.")
 	}
 }
+
+func TestStreamPlaylistM3U8(t *testing.T) {
+	var token string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/station/list/JP13.xml", "/v3/station/list/JP27.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "station_list.xml"))
+		case "/TBS/playlist.m3u8":
+			token = r.Header.Get(radikoAuthTokenHeader)
+			http.ServeFile(w, r, filepath.Join(testdataDir, "uri.m3u8"))
+		case "/QRR/playlist.m3u8":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "chunklist.m3u8"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+	c.setAuthTokenHeader("token")
+
+	defer func(s string) { liveStreamURL = s }(liveStreamURL)
+	liveStreamURL = c.URL.String() + "/%s/playlist.m3u8"
+
+	uri, err := c.StreamPlaylistM3U8(context.Background(), "TBS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "https://radiko.jp/v2/api/ts/chunklist/NejwTOkX.m3u8"; expected != uri {
+		t.Errorf("expected %s, but %s", expected, uri)
+	}
+	if expected := "token"; expected != token {
+		t.Errorf("expected %s, but %s", expected, token)
+	}
+
+	// The media playlist has no uri of a chunklist.
+	if _, err = c.StreamPlaylistM3U8(context.Background(), "QRR"); err == nil {
+		t.Error("Should detect an error.")
+	}
+
+	// QRR is not in the fixture's JP27 coverage.
+	c.SetAreaID("JP27")
+	if _, err = c.StreamPlaylistM3U8(context.Background(), "QRR"); err != ErrAreaRestricted {
+		t.Errorf("expected %s, but %v", ErrAreaRestricted, err)
+	}
+	if _, err = c.StreamPlaylistM3U8(context.Background(), "LFR"); err != ErrStationNotFound {
+		t.Errorf("expected %s, but %v", ErrStationNotFound, err)
+	}
+	if _, err = c.StreamPlaylistM3U8(context.Background(), ""); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
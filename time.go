@@ -0,0 +1,31 @@
+package radiko
+
+import (
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+// ProgramsDateString returns the date used in the program API paths.
+// It is formatted as YYYYMMDD in JST, and times before 5:00 AM
+// belong to the previous broadcast day.
+func ProgramsDateString(t time.Time) string {
+	return util.ProgramsDate(t)
+}
+
+// DatetimeString returns the datetime used in the timeshift API queries.
+// It is formatted as YYYYMMDDhhmmss in JST.
+func DatetimeString(t time.Time) string {
+	return util.Datetime(t)
+}
+
+// DayBoundaries returns the window of the broadcast day of date,
+// from 5:00 AM to 5:00 AM of the next day in JST.
+// Only the calendar date of date in JST is used,
+// so the broadcast date is not shifted even if date is before 5:00 AM.
+func DayBoundaries(date time.Time) (start, end time.Time) {
+	y, m, d := date.In(util.Location()).Date()
+	start = time.Date(y, m, d, 5, 0, 0, 0, util.Location())
+	end = start.AddDate(0, 0, 1)
+	return start, end
+}
@@ -0,0 +1,69 @@
+package radiko
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgramsDateString(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	cases := []struct {
+		t        time.Time
+		expected string
+	}{
+		{time.Date(2016, 11, 12, 23, 30, 0, 0, jst), "20161112"},
+		{time.Date(2016, 11, 13, 4, 59, 59, 0, jst), "20161112"},
+		{time.Date(2016, 11, 13, 5, 0, 0, 0, jst), "20161113"},
+		// 2016-11-12 23:00 JST
+		{time.Date(2016, 11, 12, 14, 0, 0, 0, time.UTC), "20161112"},
+	}
+	for _, c := range cases {
+		if actual := ProgramsDateString(c.t); c.expected != actual {
+			t.Errorf("expected %s, but %s", c.expected, actual)
+		}
+	}
+}
+
+func TestDatetimeString(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	cases := []struct {
+		t        time.Time
+		expected string
+	}{
+		{time.Date(2016, 11, 12, 23, 30, 15, 0, jst), "20161112233015"},
+		{time.Date(2016, 11, 13, 4, 0, 0, 0, jst), "20161113040000"},
+		// 2016-11-13 01:00 JST
+		{time.Date(2016, 11, 12, 16, 0, 0, 0, time.UTC), "20161113010000"},
+	}
+	for _, c := range cases {
+		if actual := DatetimeString(c.t); c.expected != actual {
+			t.Errorf("expected %s, but %s", c.expected, actual)
+		}
+	}
+}
+
+func TestDayBoundaries(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	cases := []struct {
+		date          time.Time
+		expectedStart time.Time
+	}{
+		{time.Date(2016, 11, 12, 0, 0, 0, 0, jst), time.Date(2016, 11, 12, 5, 0, 0, 0, jst)},
+		{time.Date(2016, 11, 12, 23, 59, 0, 0, jst), time.Date(2016, 11, 12, 5, 0, 0, 0, jst)},
+		// 2016-11-12 08:00 JST
+		{time.Date(2016, 11, 11, 23, 0, 0, 0, time.UTC), time.Date(2016, 11, 12, 5, 0, 0, 0, jst)},
+		// 2016-11-11 23:00 JST
+		{time.Date(2016, 11, 11, 14, 0, 0, 0, time.UTC), time.Date(2016, 11, 11, 5, 0, 0, 0, jst)},
+		// 2016-11-13 00:30 JST, in America/New_York still 2016-11-12
+		{time.Date(2016, 11, 12, 10, 30, 0, 0, time.FixedZone("EST", -5*60*60)), time.Date(2016, 11, 13, 5, 0, 0, 0, jst)},
+	}
+	for _, c := range cases {
+		start, end := DayBoundaries(c.date)
+		if !c.expectedStart.Equal(start) {
+			t.Errorf("expected %s, but %s", c.expectedStart, start)
+		}
+		if expected := c.expectedStart.Add(24 * time.Hour); !expected.Equal(end) {
+			t.Errorf("expected %s, but %s", expected, end)
+		}
+	}
+}
@@ -2,38 +2,512 @@ package radiko
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
 	"path"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/chikulla/go-radiko/internal/m3u8"
 	"github.com/chikulla/go-radiko/internal/util"
 )
 
+// timeshiftDays is the number of days that programs are available in timeshift.
+const timeshiftDays = 7
+
 // TimeshiftPlaylistM3U8 returns uri.
+// The program is looked up in the schedule of the broadcast day of start,
+// so a program after midnight is resolved from the previous day's schedule.
 func (c *Client) TimeshiftPlaylistM3U8(ctx context.Context, stationID string, start time.Time) (string, error) {
+	_, uri, err := c.TimeshiftProgram(ctx, stationID, start)
+	return uri, err
+}
+
+// TimeshiftSegments returns the segment urls of the program
+// which starts at start, in order.
+func (c *Client) TimeshiftSegments(ctx context.Context, stationID string, start time.Time) ([]string, error) {
+	segments, err := c.timeshiftSegments(ctx, stationID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	chunklist := make([]string, len(segments))
+	for i, s := range segments {
+		chunklist[i] = s.URI
+	}
+	return chunklist, nil
+}
+
+// EstimateTimeshift returns the number of the segments and the total duration
+// of the timeshift audio of the program which starts at start,
+// without downloading the segments.
+func (c *Client) EstimateTimeshift(ctx context.Context, stationID string, start time.Time) (segments int, dur time.Duration, err error) {
+	s, err := c.timeshiftSegments(ctx, stationID, start)
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(s), m3u8.TotalDuration(s), nil
+}
+
+// timeshiftSegments returns the segments of the program which starts at start,
+// excluding the NG range of the program.
+func (c *Client) timeshiftSegments(ctx context.Context, stationID string, start time.Time) ([]m3u8.Segment, error) {
+	prog, uri, err := c.TimeshiftProgram(ctx, stationID, start)
+	if err != nil {
+		return nil, err
+	}
+	ft, err := prog.StartTime()
+	if err != nil {
+		return nil, err
+	}
+	to, err := prog.EndTime()
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := c.getSegments(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return timeshiftableSegments(segments, []timedProg{{Prog: *prog, ft: ft, to: to}}, ft), nil
+}
+
+// TimeshiftProgram returns the program which starts at start
+// and its timeshift playlist uri.
+// If the program is not allowed in timeshift, it returns ErrTimeshiftNotAllowed.
+func (c *Client) TimeshiftProgram(ctx context.Context, stationID string, start time.Time) (*Prog, string, error) {
+	return c.timeshiftProgram(ctx, stationID, start, m3u8.GetURI)
+}
+
+// Quality selects the variant of the playlist which lists multiple bitrates.
+type Quality int
+
+const (
+	// QualityFirst selects the first variant, which radiko lists as the default.
+	QualityFirst Quality = iota
+	// QualityHighest selects the variant of the highest bandwidth.
+	QualityHighest
+	// QualityLowest selects the variant of the lowest bandwidth.
+	QualityLowest
+)
+
+// TimeshiftPlaylistM3U8WithQuality is like TimeshiftPlaylistM3U8,
+// but selects the variant by q if the playlist lists multiple bitrates.
+func (c *Client) TimeshiftPlaylistM3U8WithQuality(ctx context.Context, stationID string, start time.Time, q Quality) (string, error) {
+	pref := m3u8.VariantFirst
+	switch q {
+	case QualityHighest:
+		pref = m3u8.VariantHighest
+	case QualityLowest:
+		pref = m3u8.VariantLowest
+	}
+
+	_, uri, err := c.timeshiftProgram(ctx, stationID, start, func(r io.Reader) (string, error) {
+		return m3u8.SelectVariant(r, pref)
+	})
+	return uri, err
+}
+
+// timeshiftProgram returns the program which starts at start
+// and the uri in its timeshift playlist parsed by getURI.
+func (c *Client) timeshiftProgram(ctx context.Context, stationID string, start time.Time, getURI func(io.Reader) (string, error)) (*Prog, string, error) {
 	prog, err := c.GetProgramByStartTime(ctx, stationID, start)
 	if err != nil {
-		return "", err
+		return nil, "", err
+	}
+	if !prog.CanTimeshift() {
+		return nil, "", ErrTimeshiftNotAllowed
+	}
+
+	uri, err := c.timeshiftRangePlaylist(ctx, stationID, prog.Ft, prog.To, getURI)
+	if err != nil {
+		return nil, "", err
+	}
+	// radiko responds with no uri instead of an error for the expired programs.
+	if uri == "" && prog.timeshiftExpired(time.Now()) {
+		return nil, "", ErrTimeshiftExpired
+	}
+	return prog, uri, nil
+}
+
+// CanTimeshift reports whether the station's program which starts at start
+// is available in timeshift now.
+// If the program is not found, it returns ErrProgramNotFound.
+func (c *Client) CanTimeshift(ctx context.Context, stationID string, start time.Time) (bool, error) {
+	prog, err := c.GetProgramByStartTime(ctx, stationID, start)
+	if err != nil {
+		return false, err
+	}
+	return prog.TimeshiftAvailable(time.Now()), nil
+}
+
+// TimeshiftAvailable reports whether the program is available
+// in timeshift at now, that is, it is allowed in timeshift,
+// has ended, and started within timeshiftDays before now.
+// It returns false if Ft or To is invalid.
+func (p Prog) TimeshiftAvailable(now time.Time) bool {
+	if !p.CanTimeshift() {
+		return false
+	}
+	to, err := p.EndTime()
+	if err != nil || to.After(now) {
+		return false
+	}
+	_, err = p.StartTime()
+	return err == nil && !p.timeshiftExpired(now)
+}
+
+// timeshiftExpired reports whether the program started
+// more than timeshiftDays before now.
+func (p Prog) timeshiftExpired(now time.Time) bool {
+	ft, err := p.StartTime()
+	return err == nil && ft.Before(now.AddDate(0, 0, -timeshiftDays))
+}
+
+// RecordTimeshift downloads the station's timeshift audio
+// from start to end and writes it to w.
+// The segments in the NG ranges of the programs are skipped,
+// and the segment following them is reported as a discontinuity.
+func (c *Client) RecordTimeshift(ctx context.Context, stationID string, start, end time.Time, w io.Writer) (*DownloadReport, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return nil, err
+	}
+	if !start.Before(end) {
+		return nil, errors.New("start must be before end")
+	}
+
+	segments, err := c.recordSegments(ctx, stationID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return c.DownloadSegments(ctx, segments, w, nil)
+}
+
+// recordSegments returns the segments of the station's timeshift audio
+// from start to end, excluding the ranges not allowed in timeshift.
+func (c *Client) recordSegments(ctx context.Context, stationID string, start, end time.Time) ([]m3u8.Segment, error) {
+	progs, err := c.programsInRange(ctx, stationID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := c.timeshiftRangePlaylistM3U8(ctx, stationID,
+		util.Datetime(start), util.Datetime(end))
+	if err != nil {
+		return nil, err
+	}
+	segments, err := c.getSegments(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return timeshiftableSegments(segments, progs, start), nil
+}
+
+// timeshiftableSegments returns the segments excluding the ones
+// in the ranges of progs which are not allowed in timeshift.
+// A segment belongs to the program which contains its midpoint,
+// and the segments out of progs are kept.
+// The segment following the excluded ones starts a discontinuity.
+func timeshiftableSegments(segments []m3u8.Segment, progs []timedProg, from time.Time) []m3u8.Segment {
+	kept := make([]m3u8.Segment, 0, len(segments))
+	var skipped bool
+	for i, mid := range segmentMidpoints(segments, from) {
+		if j, ok := programAt(progs, mid); ok && !progs[j].timeshiftable(mid) {
+			skipped = true
+			continue
+		}
+
+		s := segments[i]
+		if skipped && len(kept) > 0 {
+			s.Discontinuity = true
+		}
+		skipped = false
+		kept = append(kept, s)
+	}
+	return kept
+}
+
+// segmentMidpoints returns the midpoint time of each segment.
+// The segments without EXT-X-PROGRAM-DATE-TIME are timed
+// by the durations elapsed from "from".
+func segmentMidpoints(segments []m3u8.Segment, from time.Time) []time.Time {
+	mids := make([]time.Time, len(segments))
+	elapsed := from
+	for i, s := range segments {
+		start := s.ProgramDateTime
+		if start.IsZero() {
+			start = elapsed
+		}
+		elapsed = start.Add(s.Duration)
+		mids[i] = start.Add(s.Duration / 2)
+	}
+	return mids
+}
+
+// programAt returns the index of the program in progs which contains t.
+func programAt(progs []timedProg, t time.Time) (int, bool) {
+	for i, p := range progs {
+		if !t.Before(p.ft) && t.Before(p.to) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// RecordTimeshiftParts is like RecordTimeshift, but splits the audio
+// at the discontinuities of the playlist.
+// Each part is written to the writer returned by openPart,
+// where part is numbered from 0. The writers are closed after the part is written.
+func (c *Client) RecordTimeshiftParts(ctx context.Context, stationID string, start, end time.Time, openPart func(part int) (io.WriteCloser, error)) (*DownloadReport, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return nil, err
+	}
+	if !start.Before(end) {
+		return nil, errors.New("start must be before end")
+	}
+	if openPart == nil {
+		return nil, errors.New("openPart is nil")
+	}
+
+	segments, err := c.recordSegments(ctx, stationID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DownloadReport{}
+	offset := 0
+	for i, part := range m3u8.SplitAtDiscontinuity(segments) {
+		w, err := openPart(i)
+		if err != nil {
+			return report, err
+		}
+		r, err := c.DownloadSegments(ctx, part, w, nil)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		report.Segments += r.Segments
+		report.Missing = append(report.Missing, r.Missing...)
+		for _, d := range r.Discontinuities {
+			report.Discontinuities = append(report.Discontinuities, offset+d)
+		}
+		offset += len(part)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// RecordTimeshiftSplit downloads the station's timeshift audio
+// from "from" to "to", and writes each program's audio to the writer
+// returned by openFile. The writers are closed after the program is written.
+// The programs are split at the segment boundary nearest to their start time.
+func (c *Client) RecordTimeshiftSplit(ctx context.Context, stationID string, from, to time.Time, openFile func(Prog) (io.WriteCloser, error)) error {
+	if err := ValidateStationID(stationID); err != nil {
+		return err
+	}
+	if !from.Before(to) {
+		return errors.New("from must be before to")
+	}
+	if openFile == nil {
+		return errors.New("openFile is nil")
 	}
 
-	apiEndpoint := apiPath(apiV2, "ts/playlist.m3u8")
-	req, err := c.newRequest(ctx, "POST", apiEndpoint, &Params{
+	progs, err := c.programsInRange(ctx, stationID, from, to)
+	if err != nil {
+		return err
+	}
+	if len(progs) == 0 {
+		return ErrProgramNotFound
+	}
+
+	uri, err := c.timeshiftRangePlaylistM3U8(ctx, stationID,
+		util.Datetime(from), util.Datetime(to))
+	if err != nil {
+		return err
+	}
+	segments, err := c.getSegments(ctx, uri)
+	if err != nil {
+		return err
+	}
+
+	// Assign each segment to the program which contains its midpoint,
+	// skipping the ranges which are not allowed in timeshift.
+	chunks := make([][]m3u8.Segment, len(progs))
+	for i, mid := range segmentMidpoints(segments, from) {
+		if j, ok := programAt(progs, mid); ok && progs[j].timeshiftable(mid) {
+			chunks[j] = append(chunks[j], segments[i])
+		}
+	}
+
+	for i, p := range progs {
+		if len(chunks[i]) == 0 {
+			continue
+		}
+		w, err := openFile(p.Prog)
+		if err != nil {
+			return err
+		}
+		_, err = c.DownloadSegments(ctx, chunks[i], w, nil)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type timedProg struct {
+	Prog
+	ft, to time.Time
+}
+
+func (p timedProg) timeshiftable(t time.Time) bool {
+	if !p.CanTimeshift() {
+		return false
+	}
+	for _, r := range p.TimeshiftableRanges() {
+		if r.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// programsInRange returns the station's programs which overlap from and to,
+// sorted by the start time.
+func (c *Client) programsInRange(ctx context.Context, stationID string, from, to time.Time) ([]timedProg, error) {
+	var progs []timedProg
+	for day := broadcastDayStart(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		dayProgs, err := c.GetProgramsByStation(ctx, stationID, day)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range dayProgs {
+			ft, err := util.ParseRadikoTime(p.Ft)
+			if err != nil {
+				return nil, err
+			}
+			pTo, err := util.ParseRadikoTime(p.To)
+			if err != nil {
+				return nil, err
+			}
+			if ft.Before(to) && pTo.After(from) {
+				progs = append(progs, timedProg{Prog: p, ft: ft, to: pTo})
+			}
+		}
+	}
+
+	sort.SliceStable(progs, func(i, j int) bool {
+		return progs[i].ft.Before(progs[j].ft)
+	})
+	return progs, nil
+}
+
+// RecordTimeshiftPadded is like RecordTimeshift, but starts prePad earlier
+// and ends postPad later.
+// The padded range is clamped to the broadcast days of start and end,
+// and to the range available in timeshift.
+func (c *Client) RecordTimeshiftPadded(ctx context.Context, stationID string, start, end time.Time, prePad, postPad time.Duration, w io.Writer) (*DownloadReport, error) {
+	start, end = paddedRange(time.Now(), start, end, prePad, postPad)
+	return c.RecordTimeshift(ctx, stationID, start, end, w)
+}
+
+// paddedRange returns the range expanded by the paddings and clamped.
+func paddedRange(now, start, end time.Time, prePad, postPad time.Duration) (time.Time, time.Time) {
+	dayStart := broadcastDayStart(start)
+	dayEnd := broadcastDayStart(end)
+	if end.After(dayEnd) {
+		dayEnd = dayEnd.AddDate(0, 0, 1)
+	}
+
+	paddedStart := start.Add(-prePad)
+	if paddedStart.Before(dayStart) {
+		paddedStart = dayStart
+	}
+	if oldest := now.AddDate(0, 0, -timeshiftDays); paddedStart.Before(oldest) {
+		paddedStart = oldest
+	}
+
+	paddedEnd := end.Add(postPad)
+	if paddedEnd.After(dayEnd) {
+		paddedEnd = dayEnd
+	}
+	if paddedEnd.After(now) {
+		paddedEnd = now
+	}
+	return paddedStart, paddedEnd
+}
+
+// broadcastDayStart returns the time the broadcast day of t starts at.
+func broadcastDayStart(t time.Time) time.Time {
+	d := util.BroadcastDate(t)
+	y, m, day := d.Date()
+	return time.Date(y, m, day, 5, 0, 0, 0, d.Location())
+}
+
+func (c *Client) timeshiftRangePlaylistM3U8(ctx context.Context, stationID, ft, to string) (string, error) {
+	return c.timeshiftRangePlaylist(ctx, stationID, ft, to, m3u8.GetURI)
+}
+
+// timeshiftRangePlaylist requests the timeshift playlist from ft to to,
+// and returns the uri in it parsed by getURI.
+func (c *Client) timeshiftRangePlaylist(ctx context.Context, stationID, ft, to string, getURI func(io.Reader) (string, error)) (string, error) {
+	playlistURL, err := c.timeshiftPlaylistURL(ctx, stationID)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.callURLWithAuthTokenHeader(ctx, "POST", playlistURL, &Params{
 		query: map[string]string{
 			"station_id": stationID,
-			"ft":         prog.Ft,
-			"to":         prog.To,
-			"l":          "15", // must?
+			"ft":         ft,
+			"to":         to,
+			"l":          "15",
 		},
-		setAuthToken: true,
 	})
-
-	resp, err := c.Do(req)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	return m3u8.GetURI(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", readAPIError(resp)
+	}
+	return getURI(resp.Body)
+}
+
+// timeshiftPlaylistURL returns the url to create the timeshift playlist.
+// With WithListedStreamURLs, the station's url in the station list is used
+// if it is on the host of the Client's URL.
+// Otherwise the default endpoint under the Client's URL is returned.
+func (c *Client) timeshiftPlaylistURL(ctx context.Context, stationID string) (*url.URL, error) {
+	defaultURL := c.endpointURL(apiPath(apiV2, "ts/playlist.m3u8"))
+	if !c.listedStreamURLs {
+		return defaultURL, nil
+	}
+
+	station, err := c.GetStationByID(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+	if u, ok := urlOnHost(station.playlistCreateURL(true), c.URL.Host); ok {
+		return u, nil
+	}
+	return defaultURL, nil
+}
+
+// urlOnHost parses rawurl and reports whether it is an absolute url on host.
+func urlOnHost(rawurl, host string) (*url.URL, bool) {
+	u, err := url.Parse(rawurl)
+	if err != nil || !u.IsAbs() || u.Host != host {
+		return nil, false
+	}
+	return u, true
 }
 
 // GetTimeshiftURL returns a timeshift url for web browser.
@@ -41,3 +515,119 @@ func GetTimeshiftURL(stationID string, start time.Time) string {
 	endpoint := path.Join("#!/ts", stationID, util.Datetime(start))
 	return defaultEndpoint + "/" + endpoint
 }
+
+// TimeshiftURL returns the timeshift url of the program for web browser.
+// It is like GetTimeshiftURL with the program's start time.
+// If Ft is invalid, it returns "".
+func (p Prog) TimeshiftURL(stationID string) string {
+	ft, err := p.StartTime()
+	if err != nil {
+		return ""
+	}
+	return GetTimeshiftURL(stationID, ft)
+}
+
+// ShareURL returns the radiko.jp share url of the program's timeshift.
+// It returns an empty string if the program is not allowed to be shared.
+func (p Prog) ShareURL(stationID string) string {
+	if !p.CanShare() {
+		return ""
+	}
+
+	ft := p.Ft
+	if t, err := util.ParseRadikoTime(ft); err == nil {
+		ft = util.Datetime(t)
+	}
+
+	v := url.Values{}
+	v.Set("sid", stationID)
+	v.Set("t", ft)
+	return defaultEndpoint + "/share/?" + v.Encode()
+}
+
+// ParseTimefreeURL returns the station id and the start time
+// in the timefree url, which is in the form of GetTimeshiftURL like
+// https://radiko.jp/#!/ts/TBS/20161112220000, or of ShareURL like
+// https://radiko.jp/share/?sid=TBS&t=20161112220000.
+func ParseTimefreeURL(u string) (stationID string, start time.Time, err error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var ft string
+	if q := parsed.Query(); q.Get("sid") != "" {
+		stationID, ft = q.Get("sid"), q.Get("t")
+	} else {
+		// The fragment is like "!/ts/TBS/20161112220000".
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(parsed.Fragment, "!"), "/"), "/")
+		if len(parts) != 3 || parts[0] != "ts" {
+			return "", time.Time{}, fmt.Errorf("invalid timefree url: %s", u)
+		}
+		stationID, ft = parts[1], parts[2]
+	}
+
+	if err := ValidateStationID(stationID); err != nil {
+		return "", time.Time{}, err
+	}
+	start, err = util.ParseRadikoTime(ft)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return stationID, start, nil
+}
+
+// GetProgramFromURL returns the program of the timefree url
+// parsed by ParseTimefreeURL.
+func (c *Client) GetProgramFromURL(ctx context.Context, u string) (*Prog, error) {
+	stationID, start, err := ParseTimefreeURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetProgramByStartTime(ctx, stationID, start)
+}
+
+// GetTimeshiftablePrograms returns the station's programs which are
+// still available in timeshift, sorted by the start time.
+// The programs which are not allowed in timeshift are excluded.
+// The broadcast days in the timeshift window are fetched concurrently.
+func (c *Client) GetTimeshiftablePrograms(ctx context.Context, stationID string) ([]Prog, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -timeshiftDays)
+
+	days := make([][]Prog, timeshiftDays+1)
+	err := c.parallel(ctx, len(days), func(ctx context.Context, i int) error {
+		progs, err := c.GetProgramsByStation(ctx, stationID, now.AddDate(0, 0, -i))
+		days[i] = progs
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var progs []Prog
+	for _, day := range days {
+		for _, p := range day {
+			ft, err := util.ParseRadikoTime(p.Ft)
+			if err != nil {
+				return nil, err
+			}
+			to, err := util.ParseRadikoTime(p.To)
+			if err != nil {
+				return nil, err
+			}
+			if !ft.Before(from) && !to.After(now) && p.CanTimeshift() {
+				progs = append(progs, p)
+			}
+		}
+	}
+
+	sort.SliceStable(progs, func(i, j int) bool {
+		return progs[i].Ft < progs[j].Ft
+	})
+	return progs, nil
+}
@@ -1,9 +1,20 @@
 package radiko
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
 )
 
 func TestTimeshiftPlaylistM3U8(t *testing.T) {
@@ -31,3 +42,809 @@ func TestGetTimeshiftURL(t *testing.T) {
 		t.Error("A timeshift url is empty.")
 	}
 }
+
+func TestTimeshiftPlaylistM3U8_AfterMidnight(t *testing.T) {
+	var query url.Values
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			query = r.URL.Query()
+			http.ServeFile(w, r, filepath.Join(testdataDir, "uri.m3u8"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161113030000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := c.TimeshiftPlaylistM3U8(context.Background(), "LFR", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri == "" {
+		t.Error("uri is empty.")
+	}
+
+	if expected, actual := "20161113030000", query.Get("ft"); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+	if expected, actual := "20161113050000", query.Get("to"); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+}
+
+func TestTimeshiftPlaylistM3U8_PlaylistCreateURL(t *testing.T) {
+	var query url.Values
+	var serverURL string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/station/list/JP13.xml":
+			fmt.Fprintf(w, `<stations><station><id>LFR</id><area_id>JP13</area_id>
+<url areafree="1" timefree="1"><playlist_create_url>%[1]s/areafree/playlist.m3u8</playlist_create_url></url>
+<url areafree="0" timefree="1"><playlist_create_url>%[1]s/tf/playlist.m3u8?type=b</playlist_create_url></url>
+</station></stations>`, serverURL)
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/tf/playlist.m3u8":
+			query = r.URL.Query()
+			http.ServeFile(w, r, filepath.Join(testdataDir, "uri.m3u8"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+	serverURL = c.URL.String()
+	if err := WithListedStreamURLs(true)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	start, err := util.ParseRadikoTime("20161113030000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := c.TimeshiftPlaylistM3U8(context.Background(), "LFR", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri == "" {
+		t.Error("uri is empty.")
+	}
+	if expected, actual := "b", query.Get("type"); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+	if expected, actual := "20161113030000", query.Get("ft"); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+}
+
+func TestTimeshiftPlaylistM3U8_ListedURLOnOtherHost(t *testing.T) {
+	var called bool
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/station/list/JP13.xml":
+			fmt.Fprint(w, `<stations><station><id>LFR</id><area_id>JP13</area_id>
+<url areafree="0" timefree="1"><playlist_create_url>https://example.com/tf/playlist.m3u8</playlist_create_url></url>
+</station></stations>`)
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			called = true
+			http.ServeFile(w, r, filepath.Join(testdataDir, "uri.m3u8"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+	if err := WithListedStreamURLs(true)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	start, err := util.ParseRadikoTime("20161113030000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TimeshiftPlaylistM3U8(context.Background(), "LFR", start); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Error("expected the default playlist endpoint to be called")
+	}
+}
+
+func TestTimeshiftPlaylistM3U8_ListedURLLookupError(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "uri.m3u8"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+	if err := WithListedStreamURLs(true)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	start, err := util.ParseRadikoTime("20161113030000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TimeshiftPlaylistM3U8(context.Background(), "LFR", start); err == nil {
+		t.Error("expected the station lookup error, but nil")
+	}
+}
+
+func TestGetTimeshiftablePrograms(t *testing.T) {
+	const progFormat = `<prog ft="%s" to="%s"><title>%s</title></prog>`
+
+	now := time.Now()
+	today := util.ProgramsDate(now)
+	weekAgo := util.ProgramsDate(now.AddDate(0, 0, -timeshiftDays))
+
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		// /v3/program/station/date/{date}/{stationID}.xml
+		date := path.Base(path.Dir(r.URL.Path))
+		day, err := util.ParseRadikoTime(date + "000000")
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		at := func(d time.Duration) string {
+			return util.Datetime(day.Add(d))
+		}
+
+		var progs string
+		switch date {
+		case today:
+			// not ended yet
+			progs = fmt.Sprintf(progFormat, at(5*time.Hour), at(29*time.Hour), "today")
+		case weekAgo:
+			// expired
+			progs = fmt.Sprintf(progFormat, at(-24*time.Hour), at(-23*time.Hour), "expired")
+		default:
+			progs = fmt.Sprintf(progFormat, at(22*time.Hour), at(24*time.Hour), date+"-2") +
+				fmt.Sprintf(progFormat, at(6*time.Hour), at(9*time.Hour), date+"-1")
+		}
+		fmt.Fprintf(w, `<radiko><stations><station id="TBS"><progs>%s</progs></station></stations></radiko>`, progs)
+	}))
+	defer closer()
+
+	progs, err := c.GetTimeshiftablePrograms(context.Background(), "TBS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := timeshiftDays + 1; requests != expected {
+		t.Errorf("expected %d requests, but %d", expected, requests)
+	}
+	if expected := 2 * (timeshiftDays - 1); len(progs) != expected {
+		t.Fatalf("expected %d programs, but %d", expected, len(progs))
+	}
+	for i := 1; i < len(progs); i++ {
+		if progs[i-1].Ft >= progs[i].Ft {
+			t.Errorf("programs are not sorted: %s, %s", progs[i-1].Ft, progs[i].Ft)
+		}
+	}
+}
+
+func TestRecordTimeshiftPadded(t *testing.T) {
+	var (
+		query url.Values
+		base  string
+	)
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/api/ts/playlist.m3u8":
+			query = r.URL.Query()
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=52973,CODECS=\"mp4a.40.5\"\n%s/chunklist.m3u8\n", base)
+		case "/chunklist.m3u8":
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:5,\n%s/1.aac\n#EXTINF:5,\n%s/2.aac\n#EXT-X-ENDLIST\n", base, base)
+		case "/1.aac", "/2.aac":
+			w.Write([]byte(r.URL.Path))
+		default:
+			if strings.HasPrefix(r.URL.Path, "/v3/program/station/date/") {
+				w.Write([]byte(`<radiko><stations><station id="TBS"><progs></progs></station></stations></radiko>`))
+				return
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+	base = c.URL.String()
+
+	day := broadcastDayStart(time.Now().AddDate(0, 0, -1))
+	start := day.Add(time.Minute)
+	end := day.Add(time.Hour)
+
+	var buf bytes.Buffer
+	report, err := c.RecordTimeshiftPadded(context.Background(), "TBS", start, end,
+		5*time.Minute, 10*time.Minute, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := util.Datetime(day); expected != query.Get("ft") {
+		t.Errorf("expected %s, but %s", expected, query.Get("ft"))
+	}
+	if expected := util.Datetime(end.Add(10 * time.Minute)); expected != query.Get("to") {
+		t.Errorf("expected %s, but %s", expected, query.Get("to"))
+	}
+	if report.Segments != 2 {
+		t.Errorf("expected 2 segments, but %d", report.Segments)
+	}
+	if expected := "/1.aac/2.aac"; expected != buf.String() {
+		t.Errorf("expected %s, but %s", expected, buf.String())
+	}
+}
+
+func TestPaddedRange(t *testing.T) {
+	parse := func(s string) time.Time {
+		tm, err := util.ParseRadikoTime(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	now := parse("20161113120000")
+
+	cases := []struct {
+		start, end      string
+		prePad, postPad time.Duration
+		expectedStart   string
+		expectedEnd     string
+	}{
+		// expanded
+		{"20161112220000", "20161113000000", 5 * time.Minute, 5 * time.Minute, "20161112215500", "20161113000500"},
+		// clamped at the start of the broadcast day
+		{"20161112050100", "20161112060000", 5 * time.Minute, 0, "20161112050000", "20161112060000"},
+		// clamped at the end of the broadcast day
+		{"20161113040000", "20161113045800", 0, 5 * time.Minute, "20161113040000", "20161113050000"},
+		// clamped at now
+		{"20161113110000", "20161113115800", 0, 5 * time.Minute, "20161113110000", "20161113120000"},
+		// clamped at the oldest timeshift
+		{"20161106120100", "20161106130000", 5 * time.Minute, 0, "20161106120000", "20161106130000"},
+	}
+	for _, c := range cases {
+		start, end := paddedRange(now, parse(c.start), parse(c.end), c.prePad, c.postPad)
+		if actual := util.Datetime(start); c.expectedStart != actual {
+			t.Errorf("expected %s, but %s", c.expectedStart, actual)
+		}
+		if actual := util.Datetime(end); c.expectedEnd != actual {
+			t.Errorf("expected %s, but %s", c.expectedEnd, actual)
+		}
+	}
+}
+
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestRecordTimeshiftSplit(t *testing.T) {
+	const progs = `<radiko><stations><station id="TBS"><progs>
+<prog ft="20161112200000" to="20161112200012"><title>first</title></prog>
+<prog ft="20161112200012" to="20161112200020"><title>second</title></prog>
+</progs></station></stations></radiko>`
+
+	var base string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/station/date/20161112/TBS.xml":
+			w.Write([]byte(progs))
+		case "/v2/api/ts/playlist.m3u8":
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=52973,CODECS=\"mp4a.40.5\"\n%s/chunklist.m3u8\n", base)
+		case "/chunklist.m3u8":
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:1\n")
+			for i := 0; i < 4; i++ {
+				fmt.Fprintf(w, "#EXT-X-PROGRAM-DATE-TIME:2016-11-12T20:00:%02d+09:00\n#EXTINF:5,\n%s/%d.aac\n", i*5, base, i)
+			}
+			fmt.Fprint(w, "#EXT-X-ENDLIST\n")
+		case "/0.aac", "/1.aac", "/2.aac", "/3.aac":
+			w.Write([]byte(r.URL.Path))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+	base = c.URL.String()
+
+	from, err := util.ParseRadikoTime("20161112200000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := from.Add(20 * time.Second)
+
+	files := make(map[string]*bufferCloser)
+	err = c.RecordTimeshiftSplit(context.Background(), "TBS", from, to, func(p Prog) (io.WriteCloser, error) {
+		b := &bufferCloser{}
+		files[p.Title] = b
+		return b, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"first":  "/0.aac/1.aac",
+		"second": "/2.aac/3.aac",
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %d files, but %d", len(expected), len(files))
+	}
+	for title, content := range expected {
+		f, ok := files[title]
+		if !ok {
+			t.Errorf("%s: file not found", title)
+			continue
+		}
+		if content != f.String() {
+			t.Errorf("%s: expected %s, but %s", title, content, f.String())
+		}
+		if !f.closed {
+			t.Errorf("%s: file is not closed", title)
+		}
+	}
+}
+
+func TestTimeshiftProgram(t *testing.T) {
+	var (
+		mu                  sync.Mutex
+		programs, playlists int
+	)
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml":
+			programs++
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			playlists++
+			http.ServeFile(w, r, filepath.Join(testdataDir, "uri.m3u8"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, uri, err := c.TimeshiftProgram(context.Background(), "TBS", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "20161113000000"; expected != prog.To {
+		t.Errorf("expected %s, but %s", expected, prog.To)
+	}
+	if expected := "https://radiko.jp/v2/api/ts/chunklist/NejwTOkX.m3u8"; expected != uri {
+		t.Errorf("expected %s, but %s", expected, uri)
+	}
+	if programs != 1 || playlists != 1 {
+		t.Errorf("expected 1 program fetch and 1 playlist fetch, but %d and %d", programs, playlists)
+	}
+}
+
+func TestTimeshiftProgram_Expired(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "chunklist.m3u8"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TimeshiftPlaylistM3U8(context.Background(), "TBS", start); err != ErrTimeshiftExpired {
+		t.Errorf("expected %s, but %v", ErrTimeshiftExpired, err)
+	}
+	if ok, err := c.CanTimeshift(context.Background(), "TBS", start); err != nil || ok {
+		t.Errorf("expected false, but %t %v", ok, err)
+	}
+}
+
+func TestProg_TimeshiftAvailable(t *testing.T) {
+	p := Prog{Ft: "20161112220000", To: "20161113000000"}
+	cases := []struct {
+		now      string
+		expected bool
+	}{
+		{"20161112230000", false},
+		{"20161113000000", true},
+		{"20161119215959", true},
+		{"20161119220001", false},
+	}
+	for _, c := range cases {
+		now, err := util.ParseRadikoTime(c.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := p.TimeshiftAvailable(now); c.expected != actual {
+			t.Errorf("%s: expected %t, but %t", c.now, c.expected, actual)
+		}
+	}
+
+	now, err := util.ParseRadikoTime("20161113010000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ng := p
+	ng.TsInNg = 1
+	if ng.TimeshiftAvailable(now) {
+		t.Error("the program not allowed in timeshift should not be available")
+	}
+	if (Prog{Ft: "invalid", To: "20161113000000"}).TimeshiftAvailable(now) {
+		t.Error("the program with invalid times should not be available")
+	}
+}
+
+func TestProg_ShareURL(t *testing.T) {
+	cases := []struct {
+		ft       string
+		expected string
+	}{
+		{"20161112220000", "https://radiko.jp/share/?sid=TBS&t=20161112220000"},
+		{"20161112250000", "https://radiko.jp/share/?sid=TBS&t=20161113010000"},
+	}
+	for _, c := range cases {
+		if actual := (Prog{Ft: c.ft}).ShareURL("TBS"); c.expected != actual {
+			t.Errorf("expected %s, but %s", c.expected, actual)
+		}
+	}
+}
+
+func TestProg_TimeshiftURL(t *testing.T) {
+	if expected, actual := "https://radiko.jp/#!/ts/TBS/20161113010000", (Prog{Ft: "20161112250000"}).TimeshiftURL("TBS"); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+	if actual := (Prog{}).TimeshiftURL("TBS"); actual != "" {
+		t.Errorf("expected empty, but %s", actual)
+	}
+}
+
+func TestParseTimefreeURL(t *testing.T) {
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []string{
+		GetTimeshiftURL("TBS", start),
+		(Prog{Ft: "20161112220000"}).ShareURL("TBS"),
+		"https://radiko.jp/#/ts/TBS/20161112220000",
+	}
+	for _, c := range cases {
+		stationID, actual, err := ParseTimefreeURL(c)
+		if err != nil {
+			t.Errorf("%s: %s", c, err)
+			continue
+		}
+		if stationID != "TBS" || !actual.Equal(start) {
+			t.Errorf("%s: expected TBS %s, but %s %s", c, start, stationID, actual)
+		}
+	}
+
+	for _, c := range []string{
+		"https://radiko.jp/",
+		"https://radiko.jp/#!/live/TBS",
+		"https://radiko.jp/#!/ts/tbs/20161112220000",
+		"https://radiko.jp/#!/ts/TBS/invalid",
+		"https://radiko.jp/share/?sid=TBS",
+	} {
+		if _, _, err := ParseTimefreeURL(c); err == nil {
+			t.Errorf("%s: Should detect an error.", c)
+		}
+	}
+}
+
+func TestGetProgramFromURL(t *testing.T) {
+	c, closer := newTestClient(t, serveTestdata("programs.xml"))
+	defer closer()
+
+	prog, err := c.GetProgramFromURL(context.Background(), "https://radiko.jp/#!/ts/TBS/20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "20161112220000"; expected != prog.Ft {
+		t.Errorf("expected %s, but %s", expected, prog.Ft)
+	}
+}
+
+func TestGetTimeshiftablePrograms_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		cancel()
+		w.Write([]byte(`<radiko><stations><station id="TBS"><progs></progs></station></stations></radiko>`))
+	}))
+	defer closer()
+	if err := WithMaxConcurrency(1)(c); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := c.GetTimeshiftablePrograms(ctx, "TBS")
+	if err == nil {
+		t.Error("Should detect an error.")
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, but %d", requests)
+	}
+}
+
+func TestRecordTimeshiftParts(t *testing.T) {
+	var base string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/api/ts/playlist.m3u8":
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=52973,CODECS=\"mp4a.40.5\"\n%s/chunklist.m3u8\n", base)
+		case "/chunklist.m3u8":
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:5,\n%s/1.aac\n#EXT-X-DISCONTINUITY\n#EXTINF:5,\n%s/2.aac\n#EXTINF:5,\n%s/3.aac\n#EXT-X-ENDLIST\n", base, base, base)
+		case "/1.aac", "/2.aac", "/3.aac":
+			w.Write([]byte(r.URL.Path))
+		default:
+			if strings.HasPrefix(r.URL.Path, "/v3/program/station/date/") {
+				w.Write([]byte(`<radiko><stations><station id="TBS"><progs></progs></station></stations></radiko>`))
+				return
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+	base = c.URL.String()
+
+	start := time.Now().Add(-2 * time.Hour)
+	end := start.Add(time.Hour)
+
+	var parts []*bufferCloser
+	report, err := c.RecordTimeshiftParts(context.Background(), "TBS", start, end, func(part int) (io.WriteCloser, error) {
+		b := &bufferCloser{}
+		parts = append(parts, b)
+		return b, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Segments != 3 {
+		t.Errorf("expected 3 segments, but %d", report.Segments)
+	}
+	if len(report.Discontinuities) != 1 || report.Discontinuities[0] != 1 {
+		t.Errorf("expected [1], but %v", report.Discontinuities)
+	}
+
+	expected := []string{"/1.aac", "/2.aac/3.aac"}
+	if len(parts) != len(expected) {
+		t.Fatalf("expected %d parts, but %d", len(expected), len(parts))
+	}
+	for i := range expected {
+		if expected[i] != parts[i].String() {
+			t.Errorf("expected %s, but %s", expected[i], parts[i].String())
+		}
+	}
+}
+
+func TestRecordTimeshift_SkipNG(t *testing.T) {
+	var (
+		base    string
+		mu      sync.Mutex
+		fetched []string
+	)
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/station/date/20161112/TBS.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "program_ng.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=52973,CODECS=\"mp4a.40.5\"\n%s/chunklist.m3u8\n", base)
+		case "/chunklist.m3u8":
+			// The second segment is in the NG range from 20:30 to 21:00.
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:1800\n#EXT-X-MEDIA-SEQUENCE:1\n")
+			fmt.Fprint(w, "#EXT-X-PROGRAM-DATE-TIME:2016-11-12T20:25:00+09:00\n#EXTINF:300,\n0.aac\n")
+			fmt.Fprint(w, "#EXT-X-PROGRAM-DATE-TIME:2016-11-12T20:30:00+09:00\n#EXTINF:1800,\n1.aac\n")
+			fmt.Fprint(w, "#EXT-X-PROGRAM-DATE-TIME:2016-11-12T21:00:00+09:00\n#EXTINF:300,\n2.aac\n")
+			fmt.Fprint(w, "#EXT-X-ENDLIST\n")
+		case "/0.aac", "/1.aac", "/2.aac":
+			mu.Lock()
+			fetched = append(fetched, r.URL.Path)
+			mu.Unlock()
+			w.Write([]byte(r.URL.Path))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+	base = c.URL.String()
+
+	start, err := util.ParseRadikoTime("20161112202500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	report, err := c.RecordTimeshift(context.Background(), "TBS", start, start.Add(40*time.Minute), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/0.aac/2.aac"; expected != buf.String() {
+		t.Errorf("expected %s, but %s", expected, buf.String())
+	}
+	for _, p := range fetched {
+		if p == "/1.aac" {
+			t.Errorf("the segment in the NG range is fetched: %v", fetched)
+		}
+	}
+	if len(report.Discontinuities) != 1 || report.Discontinuities[0] != 1 {
+		t.Errorf("expected [1], but %v", report.Discontinuities)
+	}
+}
+
+func TestTimeshiftSegments(t *testing.T) {
+	var base string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=52973,CODECS=\"mp4a.40.5\"\n%s/chunk/list.m3u8\n", base)
+		case "/chunk/list.m3u8":
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:5,\n1.aac\n#EXTINF:5,\n/sound/2.aac\n#EXTINF:5,\nhttp://media.radiko.jp/3.aac\n#EXT-X-ENDLIST\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+	base = c.URL.String()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments, err := c.TimeshiftSegments(context.Background(), "TBS", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		base + "/chunk/1.aac",
+		base + "/sound/2.aac",
+		"http://media.radiko.jp/3.aac",
+	}
+	if len(segments) != len(expected) {
+		t.Fatalf("expected %v, but %v", expected, segments)
+	}
+	for i := range expected {
+		if expected[i] != segments[i] {
+			t.Errorf("expected %s, but %s", expected[i], segments[i])
+		}
+	}
+}
+
+func TestEstimateTimeshift(t *testing.T) {
+	var base string
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=52973,CODECS=\"mp4a.40.5\"\n%s/chunk/list.m3u8\n", base)
+		case "/chunk/list.m3u8":
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:5,\n1.aac\n#EXTINF:5,\n2.aac\n#EXTINF:2.5,\n3.aac\n#EXT-X-ENDLIST\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+	base = c.URL.String()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments, dur, err := c.EstimateTimeshift(context.Background(), "TBS", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 3; expected != segments {
+		t.Errorf("expected %d, but %d", expected, segments)
+	}
+	if expected := 12500 * time.Millisecond; expected != dur {
+		t.Errorf("expected %s, but %s", expected, dur)
+	}
+}
+
+func TestTimeshiftPlaylistM3U8WithQuality(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "uri_variants.m3u8"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		quality  Quality
+		expected string
+	}{
+		{QualityFirst, "https://radiko.jp/v2/api/ts/chunklist/medium.m3u8"},
+		{QualityHighest, "https://radiko.jp/v2/api/ts/chunklist/high.m3u8"},
+		{QualityLowest, "https://radiko.jp/v2/api/ts/chunklist/low.m3u8"},
+	}
+	for _, tt := range cases {
+		uri, err := c.TimeshiftPlaylistM3U8WithQuality(context.Background(), "TBS", start, tt.quality)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.expected != uri {
+			t.Errorf("expected %s, but %s", tt.expected, uri)
+		}
+	}
+}
+
+func TestTimeshiftPlaylistM3U8_Forbidden(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/program/date/20161112/JP13.xml":
+			http.ServeFile(w, r, filepath.Join(testdataDir, "programs.xml"))
+		case "/v2/api/ts/playlist.m3u8":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, "forbidden")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+
+	start, err := util.ParseRadikoTime("20161112220000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := c.TimeshiftPlaylistM3U8(context.Background(), "TBS", start)
+	statusErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("expected APIError, but %v (uri: %q)", err, uri)
+	}
+	if statusErr.StatusCode != http.StatusForbidden || statusErr.Body != "forbidden" {
+		t.Errorf("unexpected error: %s", statusErr)
+	}
+}
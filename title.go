@@ -0,0 +1,50 @@
+package radiko
+
+import (
+	"regexp"
+	"strings"
+)
+
+// titleDecoration matches the decorations of a program title,
+// such as "【再】", "[新]", "(再)", "第12回" and "#12".
+var titleDecoration = regexp.MustCompile(`【[^】]*】|\[[^\]]*\]|\((再|新|終|生)\)|第\s*\d+\s*[回話]|#\s*\d+`)
+
+// NormalizeTitle returns the title without its decorations
+// for matching the airings of a program.
+// The full-width ASCII characters are converted to half-width,
+// the decorations are stripped, the spaces are collapsed
+// and the letters are lower-cased.
+func NormalizeTitle(t string) string {
+	t = strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case r >= '！' && r <= '～':
+			return r - '！' + '!'
+		}
+		return r
+	}, t)
+	t = titleDecoration.ReplaceAllString(t, " ")
+	return strings.ToLower(strings.Join(strings.Fields(t), " "))
+}
+
+// FindProgramsByTitle returns the programs whose title matches title
+// after NormalizeTitle.
+func (s Stations) FindProgramsByTitle(title string) []ProgWithStation {
+	title = NormalizeTitle(title)
+
+	var progs []ProgWithStation
+	for _, station := range s {
+		for _, p := range station.programs() {
+			if NormalizeTitle(p.Title) != title {
+				continue
+			}
+			progs = append(progs, ProgWithStation{
+				StationID:   station.ID,
+				StationName: station.Name,
+				Prog:        p,
+			})
+		}
+	}
+	return progs
+}
@@ -0,0 +1,42 @@
+package radiko
+
+import "testing"
+
+func TestNormalizeTitle(t *testing.T) {
+	cases := []struct {
+		title    string
+		expected string
+	}{
+		{"JUNK 爆笑問題カーボーイ", "junk 爆笑問題カーボーイ"},
+		{"【再】JUNK 爆笑問題カーボーイ", "junk 爆笑問題カーボーイ"},
+		{"ＪＵＮＫ　爆笑問題カーボーイ（再）", "junk 爆笑問題カーボーイ"},
+		{"JUNK 爆笑問題カーボーイ 第123回", "junk 爆笑問題カーボーイ"},
+		{"JUNK 爆笑問題カーボーイ ＃123 [新]", "junk 爆笑問題カーボーイ"},
+		{"  JUNK   爆笑問題カーボーイ  ", "junk 爆笑問題カーボーイ"},
+	}
+	for _, c := range cases {
+		if actual := NormalizeTitle(c.title); c.expected != actual {
+			t.Errorf("%s: expected %q, but %q", c.title, c.expected, actual)
+		}
+	}
+}
+
+func TestStations_FindProgramsByTitle(t *testing.T) {
+	stations := Stations{
+		{ID: "TBS", Name: "TBSラジオ", Progs: Progs{Progs: []Prog{
+			{Ft: "20161115010000", Title: "JUNK 爆笑問題カーボーイ"},
+			{Ft: "20161115030000", Title: "JUNK 伊集院光・深夜の馬鹿力"},
+		}}},
+		{ID: "TBS", Name: "TBSラジオ", Progs: Progs{Progs: []Prog{
+			{Ft: "20161119130000", Title: "【再】ＪＵＮＫ　爆笑問題カーボーイ"},
+		}}},
+	}
+
+	progs := stations.FindProgramsByTitle("JUNK 爆笑問題カーボーイ")
+	if expected := 2; len(progs) != expected {
+		t.Fatalf("expected %d programs, but %d", expected, len(progs))
+	}
+	if expected := "20161119130000"; progs[1].Prog.Ft != expected {
+		t.Errorf("expected %s, but %s", expected, progs[1].Prog.Ft)
+	}
+}
@@ -0,0 +1,279 @@
+package radiko
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WatchNowPlaying polls GetNowPrograms every interval,
+// and sends the station's current program when it changes.
+// The channel is closed when ctx is canceled.
+func (c *Client) WatchNowPlaying(ctx context.Context, stationID string, interval time.Duration, opts ...WatchOption) (<-chan *Prog, error) {
+	if err := ValidateStationID(stationID); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+
+	w := &watchConfig{}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	ch := make(chan *Prog)
+	go func() {
+		defer close(ch)
+
+		var (
+			last  string
+			delay time.Duration
+		)
+		for {
+			var changed bool
+			prog, err := c.GetNowOnAir(ctx, stationID)
+			if err != nil {
+				c.logf("radiko: failed to get the now program: %s", err)
+				prog = nil
+			} else if h := prog.Hash(); h != last {
+				last = h
+				changed = true
+				select {
+				case ch <- prog:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			delay = w.nextInterval(interval, delay, changed, prog, time.Now())
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// WatchOption configures WatchNowPlaying.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	maxInterval time.Duration
+}
+
+// WithPollBackoff doubles the poll interval of WatchNowPlaying
+// up to maxInterval while the program is unchanged,
+// and resets it to the base interval after the program changes.
+// The poll is never later than just after the end of the current program,
+// so the next program is still caught promptly.
+// If maxInterval is not greater than the base interval, it has no effect.
+func WithPollBackoff(maxInterval time.Duration) WatchOption {
+	return func(w *watchConfig) {
+		w.maxInterval = maxInterval
+	}
+}
+
+// watchTransitionDelay is the delay after the end of the program
+// to poll the next one, since radiko updates the now programs with a lag.
+const watchTransitionDelay = 5 * time.Second
+
+// nextInterval returns the delay until the next poll.
+// prev is the previous delay, and prog is the program polled at now,
+// which is nil if the poll failed.
+func (w *watchConfig) nextInterval(base, prev time.Duration, changed bool, prog *Prog, now time.Time) time.Duration {
+	if w.maxInterval <= base || changed || prog == nil {
+		return base
+	}
+
+	next := prev * 2
+	if next > w.maxInterval {
+		next = w.maxInterval
+	}
+	if to, err := prog.EndTime(); err == nil {
+		if untilEnd := to.Sub(now) + watchTransitionDelay; untilEnd < next {
+			next = untilEnd
+		}
+	}
+	if next < base {
+		next = base
+	}
+	return next
+}
+
+// currentProgram returns the program of the station which is on the air at now.
+// If no program contains now, the first program of the station is returned.
+func currentProgram(stations Stations, stationID string, now time.Time) (*Prog, error) {
+	for _, s := range stations {
+		if s.ID != stationID {
+			continue
+		}
+		progs := s.programs()
+		if len(progs) == 0 {
+			break
+		}
+		for i := range progs {
+			if progs[i].IsOnAir(now) {
+				return &progs[i], nil
+			}
+		}
+		return &progs[0], nil
+	}
+	return nil, ErrProgramNotFound
+}
+
+// WatchNowPlayingWithErrors is like WatchNowPlaying, but sends the errors
+// of the polls to the error channel instead of logging them,
+// and detects the change of the program by its start time.
+// Both channels must be received from, and they are closed
+// when ctx is canceled.
+func (c *Client) WatchNowPlayingWithErrors(ctx context.Context, stationID string, interval time.Duration) (<-chan Prog, <-chan error) {
+	progs := make(chan Prog)
+	errs := make(chan error, 1)
+	if err := ValidateStationID(stationID); err != nil {
+		errs <- err
+		close(progs)
+		close(errs)
+		return progs, errs
+	}
+	if interval <= 0 {
+		errs <- errors.New("interval must be positive")
+		close(progs)
+		close(errs)
+		return progs, errs
+	}
+
+	go func() {
+		defer close(progs)
+		defer close(errs)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last string
+		for {
+			prog, err := c.GetNowOnAir(ctx, stationID)
+			switch {
+			case err != nil:
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+			case prog.Ft != last:
+				last = prog.Ft
+				select {
+				case progs <- *prog:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return progs, errs
+}
+
+// Target is a station to watch in WatchNowPlayingMulti.
+type Target struct {
+	// AreaID is the area to fetch the now programs of.
+	// If it is empty, the area set by WithAreaContext or the Client's areaID is used.
+	AreaID    string
+	StationID string
+}
+
+// NowEvent is sent by WatchNowPlayingMulti when the target's program changes.
+type NowEvent struct {
+	Area    string
+	Station string
+	Prog    *Prog
+}
+
+// WatchNowPlayingMulti is like WatchNowPlaying, but watches multiple targets.
+// The now programs are fetched once per area every interval,
+// and the requests for the areas are staggered across the interval.
+// The channel is closed when ctx is canceled.
+func (c *Client) WatchNowPlayingMulti(ctx context.Context, targets []Target, interval time.Duration) (<-chan NowEvent, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("targets is empty")
+	}
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+
+	var areas []string
+	byArea := make(map[string][]Target)
+	for _, t := range targets {
+		if err := ValidateStationID(t.StationID); err != nil {
+			return nil, err
+		}
+		if t.AreaID == "" {
+			t.AreaID = c.contextAreaID(ctx)
+		}
+		if _, ok := byArea[t.AreaID]; !ok {
+			areas = append(areas, t.AreaID)
+		}
+		byArea[t.AreaID] = append(byArea[t.AreaID], t)
+	}
+	stagger := interval / time.Duration(len(areas))
+	// time.NewTicker panics unless the duration is positive.
+	if stagger <= 0 {
+		stagger = interval
+	}
+
+	ch := make(chan NowEvent)
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(stagger)
+		defer ticker.Stop()
+
+		last := make(map[Target]string)
+		for i := 0; ; i = (i + 1) % len(areas) {
+			area := areas[i]
+			stations, err := c.GetNowProgramsForArea(ctx, area)
+			if err != nil {
+				c.logf("radiko: failed to get the now programs of %s: %s", area, err)
+			} else if !c.sendNowEvents(ctx, ch, area, stations, byArea[area], last) {
+				return
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// sendNowEvents sends NowEvent for each target whose program has changed
+// from the one in last. It returns false if ctx is done.
+func (c *Client) sendNowEvents(ctx context.Context, ch chan<- NowEvent, area string, stations Stations, targets []Target, last map[Target]string) bool {
+	for _, t := range targets {
+		prog, err := currentProgram(stations, t.StationID, time.Now())
+		if err != nil {
+			c.logf("radiko: failed to get the now program of %s: %s", t.StationID, err)
+			continue
+		}
+		if h := prog.Hash(); h != last[t] {
+			last[t] = h
+			select {
+			case ch <- NowEvent{Area: area, Station: t.StationID, Prog: prog}:
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,314 @@
+package radiko
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chikulla/go-radiko/internal/util"
+)
+
+const nowProgramsFormat = `<?xml version="1.0" encoding="UTF-8"?>
+<radiko>
+  <stations>
+    <station id="TBS">
+      <name>TBSラジオ</name>
+      <scd>
+        <progs>
+          <date>20161112</date>
+          <prog ft="%s" to="%s" ftl="0000" tol="0000" dur="3600">
+            <title>%s</title>
+          </prog>
+        </progs>
+      </scd>
+    </station>
+  </stations>
+</radiko>`
+
+func TestWatchNowPlaying(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+
+		if n <= 2 {
+			fmt.Fprintf(w, nowProgramsFormat, "20161112200000", "20161112210000", "first")
+			return
+		}
+		fmt.Fprintf(w, nowProgramsFormat, "20161112210000", "20161112220000", "second")
+	}))
+	defer closer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := c.WatchNowPlaying(ctx, "TBS", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case prog := <-ch:
+			if prog.Title != expected {
+				t.Errorf("expected %s, but %s", expected, prog.Title)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for %s", expected)
+		}
+	}
+
+	select {
+	case prog := <-ch:
+		t.Errorf("unexpected emission: %v", prog)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("channel should be closed.")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("channel is not closed after cancel.")
+	}
+}
+
+func TestWatchNowPlaying_InvalidArgs(t *testing.T) {
+	c, closer := newTestClient(t, http.NotFoundHandler())
+	defer closer()
+
+	if _, err := c.WatchNowPlaying(context.Background(), "", time.Second); err == nil {
+		t.Error("Should detect an error.")
+	}
+	if _, err := c.WatchNowPlaying(context.Background(), "TBS", 0); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestWatchConfig_NextInterval(t *testing.T) {
+	const base = time.Minute
+	now := time.Date(2016, 11, 12, 20, 0, 0, 0, util.Location())
+	long := &Prog{Ft: "20161112200000", To: "20161112220000"}
+	short := &Prog{Ft: "20161112195000", To: "20161112200300"}
+	overrun := &Prog{Ft: "20161112180000", To: "20161112195000"}
+
+	w := &watchConfig{maxInterval: 16 * time.Minute}
+	cases := []struct {
+		prev     time.Duration
+		changed  bool
+		prog     *Prog
+		expected time.Duration
+	}{
+		{0, true, long, base},
+		{base, false, long, 2 * base},
+		{8 * base, false, long, 16 * base},
+		{16 * base, false, long, 16 * base},
+		{16 * base, true, long, base},
+		{16 * base, false, nil, base},
+		{4 * base, false, short, 3*time.Minute + watchTransitionDelay},
+		{4 * base, false, overrun, base},
+	}
+	for i, c := range cases {
+		if actual := w.nextInterval(base, c.prev, c.changed, c.prog, now); c.expected != actual {
+			t.Errorf("%d: expected %s, but %s", i, c.expected, actual)
+		}
+	}
+
+	// Disabled
+	if actual := (&watchConfig{}).nextInterval(base, 8*base, false, long, now); actual != base {
+		t.Errorf("expected %s, but %s", base, actual)
+	}
+}
+
+func TestWatchNowPlayingWithErrors(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+
+		switch {
+		case n == 2:
+			w.WriteHeader(http.StatusInternalServerError)
+		case n <= 3:
+			fmt.Fprintf(w, nowProgramsFormat, "20161112200000", "20161112210000", "first")
+		default:
+			fmt.Fprintf(w, nowProgramsFormat, "20161112210000", "20161112220000", "second")
+		}
+	}))
+	defer closer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	progs, errs := c.WatchNowPlayingWithErrors(ctx, "TBS", 10*time.Millisecond)
+
+	var events []string
+	for len(events) < 3 {
+		select {
+		case prog := <-progs:
+			events = append(events, prog.Title)
+		case err := <-errs:
+			if err == nil {
+				t.Fatal("error channel is closed.")
+			}
+			events = append(events, "error")
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out: %v", events)
+		}
+	}
+	if expected, actual := "first error second", strings.Join(events, " "); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+
+	cancel()
+	for range progs {
+	}
+	if _, ok := <-errs; ok {
+		t.Error("channel should be closed.")
+	}
+
+	progs, errs = c.WatchNowPlayingWithErrors(context.Background(), "", time.Second)
+	if err := <-errs; err == nil {
+		t.Error("Should detect an error.")
+	}
+	if _, ok := <-progs; ok {
+		t.Error("channel should be closed.")
+	}
+}
+
+const nowStationFormat = `<?xml version="1.0" encoding="UTF-8"?>
+<radiko>
+  <stations>
+    <station id="%s">
+      <scd>
+        <progs>
+          <prog ft="20161112200000" to="20161112210000" ftl="2000" tol="2100" dur="3600">
+            <title>%s</title>
+          </prog>
+        </progs>
+      </scd>
+    </station>
+  </stations>
+</radiko>`
+
+func TestWatchNowPlayingMulti(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]int)
+	)
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		area := r.URL.Query().Get("area_id")
+		mu.Lock()
+		calls[area]++
+		n := calls[area]
+		mu.Unlock()
+
+		switch area {
+		case "JP13":
+			// Changes on the second call.
+			title := "TBS first"
+			if n >= 2 {
+				title = "TBS second"
+			}
+			fmt.Fprintf(w, nowStationFormat, "TBS", title)
+		case "JP27":
+			// Never changes.
+			fmt.Fprintf(w, nowStationFormat, "ABC", "ABC first")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer closer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := c.WatchNowPlayingMulti(ctx, []Target{
+		{AreaID: "JP13", StationID: "TBS"},
+		{AreaID: "JP27", StationID: "ABC"},
+	}, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := make(map[string][]string)
+	for i := 0; i < 3; i++ {
+		select {
+		case e := <-ch:
+			titles[e.Station] = append(titles[e.Station], e.Prog.Title)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for events: %v", titles)
+		}
+	}
+
+	expected := map[string][]string{
+		"TBS": {"TBS first", "TBS second"},
+		"ABC": {"ABC first"},
+	}
+	for station, e := range expected {
+		if fmt.Sprint(e) != fmt.Sprint(titles[station]) {
+			t.Errorf("%s: expected %v, but %v", station, e, titles[station])
+		}
+	}
+
+	select {
+	case e := <-ch:
+		t.Errorf("unexpected emission: %v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatchNowPlayingMulti_InvalidArgs(t *testing.T) {
+	c, closer := newTestClient(t, http.NotFoundHandler())
+	defer closer()
+
+	if _, err := c.WatchNowPlayingMulti(context.Background(), nil, time.Second); err == nil {
+		t.Error("Should detect an error.")
+	}
+	if _, err := c.WatchNowPlayingMulti(context.Background(), []Target{{AreaID: "JP13"}}, time.Second); err == nil {
+		t.Error("Should detect an error.")
+	}
+	if _, err := c.WatchNowPlayingMulti(context.Background(), []Target{{StationID: "TBS"}}, 0); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestWatchNowPlayingMulti_ShortInterval(t *testing.T) {
+	c, closer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, nowStationFormat, "TBS", "TBS first")
+	}))
+	defer closer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The interval is shorter than the number of the areas in nanoseconds.
+	ch, err := c.WatchNowPlayingMulti(ctx, []Target{
+		{AreaID: "JP13", StationID: "TBS"},
+		{AreaID: "JP27", StationID: "TBS"},
+	}, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ch:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+}